@@ -36,13 +36,15 @@ var (
 	typeTime     = reflect.TypeOf(time.Time{})
 )
 
-// Decode decodes a configuration into a struct or map, see `Config.Decode`.
+// Decode decodes a configuration into a struct or map, see `Config.Decode`. A
+// DecodeError includes the position of the invalid value, see
+// Document.Decode.
 func Decode(r io.Reader, dst interface{}) error {
-	c, err := Parse(r)
+	doc, err := ParseDocument(r)
 	if err != nil {
 		return err
 	}
-	return c.Decode(dst)
+	return decode(doc, dst, "Config.Decode", false)
 }
 
 // DecodeValue decodes a single configuration value into a variable.
@@ -57,12 +59,14 @@ func DecodeValue(value string, dst interface{}) error {
 		return errors.New("ini: can't change value of destination value")
 	}
 
-	return setReflectValue(&v, value)
+	return setReflectValue(&v, value, false)
 }
 
-func setReflectValue(keyValue *reflect.Value, value string) error {
+// SetReflectValue sets the reflected value from value. ExtendedBools is
+// Options.ExtendedBools.
+func setReflectValue(keyValue *reflect.Value, value string, extendedBools bool) error {
 	if keyValue.Kind() == reflect.Slice {
-		return setSlice(keyValue, value)
+		return setSlice(keyValue, value, extendedBools)
 	}
 
 	// Special time cases.
@@ -77,7 +81,7 @@ func setReflectValue(keyValue *reflect.Value, value string) error {
 	case kindString:
 		keyValue.SetString(value)
 	case kindBool:
-		return setBool(keyValue, value)
+		return setBool(keyValue, value, extendedBools)
 	case kindInt, kindInt8, kindInt16, kindInt32, kindInt64:
 		return setInt(keyValue, value)
 	case kindUint, kindUint8, kindUint16, kindUint32, kindUint64:
@@ -89,10 +93,13 @@ func setReflectValue(keyValue *reflect.Value, value string) error {
 	return nil
 }
 
-// SetSlice sets a slice of reflected value.
-func setSlice(keyValue *reflect.Value, value string) error {
-	values := getValues(value)
+// SetSlice sets a slice of reflected value from a comma separated list.
+func setSlice(keyValue *reflect.Value, value string, extendedBools bool) error {
+	return setSliceValues(keyValue, getValues(value), extendedBools)
+}
 
+// SetSliceValues sets a slice of reflected value, with each value as element.
+func setSliceValues(keyValue *reflect.Value, values []string, extendedBools bool) error {
 	// Special time cases.
 	switch keyValue.Type().Elem() {
 	case typeDuration:
@@ -106,7 +113,7 @@ func setSlice(keyValue *reflect.Value, value string) error {
 	case kindString:
 		keyValue.Set(reflect.ValueOf(values))
 	case kindBool:
-		return setBools(keyValue, values)
+		return setBools(keyValue, values, extendedBools)
 	case kindInt:
 		return setInts(keyValue, values)
 	case kindInt8:
@@ -136,11 +143,11 @@ func setSlice(keyValue *reflect.Value, value string) error {
 	return nil
 }
 
-func setBools(keyValue *reflect.Value, values []string) error {
+func setBools(keyValue *reflect.Value, values []string, extendedBools bool) error {
 	var bs = make([]bool, len(values))
 	for i, value := range values {
 		bValue := reflect.Indirect(reflect.ValueOf(&bs[i]))
-		if err := setBool(&bValue, value); err != nil {
+		if err := setBool(&bValue, value, extendedBools); err != nil {
 			return err
 		}
 	}
@@ -149,10 +156,10 @@ func setBools(keyValue *reflect.Value, values []string) error {
 	return nil
 }
 
-func setBool(keyValue *reflect.Value, value string) error {
-	b, err := strconv.ParseBool(value)
+func setBool(keyValue *reflect.Value, value string, extendedBools bool) error {
+	b, err := parseBool(value, extendedBools)
 	if err != nil {
-		return createCovertionError(value, keyValue.Kind().String())
+		return createCovertionError(value, keyValue.Kind().String(), err)
 	}
 
 	keyValue.SetBool(b)
@@ -227,7 +234,7 @@ func setInt64s(keyValue *reflect.Value, values []string) error {
 func setInt(keyValue *reflect.Value, value string) error {
 	n, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return createCovertionError(value, keyValue.Kind().String())
+		return createCovertionError(value, keyValue.Kind().String(), err)
 	}
 	n64 := n
 
@@ -307,7 +314,7 @@ func setUint64s(keyValue *reflect.Value, values []string) error {
 func setUint(keyValue *reflect.Value, value string) error {
 	n, err := strconv.ParseUint(value, 10, 64)
 	if err != nil {
-		return createCovertionError(value, keyValue.Kind().String())
+		return createCovertionError(value, keyValue.Kind().String(), err)
 	}
 	nu64 := n
 
@@ -348,7 +355,7 @@ func setFloat64s(keyValue *reflect.Value, values []string) error {
 func setFloat(keyValue *reflect.Value, value string) error {
 	f, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		return createCovertionError(value, keyValue.Kind().String())
+		return createCovertionError(value, keyValue.Kind().String(), err)
 	}
 
 	if keyValue.OverflowFloat(f) {
@@ -375,7 +382,7 @@ func setDurations(keyValue *reflect.Value, values []string) error {
 func setDuration(keyValue *reflect.Value, value string) error {
 	duration, err := time.ParseDuration(value)
 	if err != nil {
-		return createCovertionError(value, "time.Duration")
+		return createCovertionError(value, "time.Duration", err)
 	}
 
 	durationValue := reflect.ValueOf(duration)
@@ -397,8 +404,10 @@ func setTimes(keyValue *reflect.Value, values []string) error {
 }
 
 func setTime(keyValue *reflect.Value, value string) error {
+	var err error
 	for _, format := range timeFormats {
-		t, err := time.Parse(format, value)
+		var t time.Time
+		t, err = time.Parse(format, value)
 		if err == nil {
 			timeValue := reflect.ValueOf(t)
 			keyValue.Set(timeValue)
@@ -406,7 +415,20 @@ func setTime(keyValue *reflect.Value, value string) error {
 		}
 	}
 
-	return createCovertionError(value, "time.Time")
+	// Only the error of the last format is returned.
+	return createCovertionError(value, "time.Time", err)
+}
+
+// InvalidValue returns the first value that can't be decoded as element of a
+// slice of type typ.
+func invalidValue(typ reflect.Type, values []string, extendedBools bool) string {
+	for _, value := range values {
+		element := reflect.New(typ).Elem()
+		if setSliceValues(&element, []string{value}, extendedBools) != nil {
+			return value
+		}
+	}
+	return strings.Join(values, ", ")
 }
 
 func getValues(value string) []string {
@@ -416,3 +438,18 @@ func getValues(value string) []string {
 	}
 	return values
 }
+
+// ParseBool parses a boolean like `strconv.ParseBool`. With extendedBools it
+// also accepts "yes", "no", "on" and "off", in any case, as used by many ini
+// dialects, see Options.ExtendedBools.
+func parseBool(value string, extendedBools bool) (bool, error) {
+	if extendedBools {
+		switch strings.ToLower(value) {
+		case "yes", "on":
+			return true, nil
+		case "no", "off":
+			return false, nil
+		}
+	}
+	return strconv.ParseBool(value)
+}
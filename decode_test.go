@@ -303,6 +303,45 @@ func TestDecodeValueOverflowError(t *testing.T) {
 	}
 }
 
+func TestDecodeExtendedBools(t *testing.T) {
+	t.Parallel()
+	content := "debug = Yes\nverbose = off\nflags = on, NO, 1\n"
+	type boolTestData struct {
+		Debug   bool
+		Verbose bool
+		Flags   []bool
+	}
+
+	// By default only the values accepted by strconv.ParseBool are booleans.
+	var got boolTestData
+	expected := "to type bool"
+	if err := Decode(strings.NewReader(content), &got); err == nil ||
+		!strings.Contains(err.Error(), expected) {
+		t.Fatalf("Expected an error containing %q, but got %v", expected, err)
+	}
+	var b bool
+	if err := DecodeValue("on", &b); err == nil {
+		t.Fatal("Expected an error decoding \"on\" as a boolean")
+	}
+
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), Options{ExtendedBools: true})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	} else if err := doc.Decode(&got); err != nil {
+		t.Fatalf("Unexpected error decoding: %s", err.Error())
+	}
+	expectedData := boolTestData{Debug: true, Flags: []bool{true, false, true}}
+	if !reflect.DeepEqual(got, expectedData) {
+		t.Fatalf("Expected %+v, but got %+v", expectedData, got)
+	}
+
+	// Config.Decode doesn't know the options.
+	c := doc.Config()
+	if err := c.Decode(&got); err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("Expected an error containing %q, but got %v", expected, err)
+	}
+}
+
 type tagTestData struct {
 	MyKey1   string `ini:"my_key1"`
 	MyKey2   string
@@ -350,3 +389,142 @@ func TestDecodeTag(t *testing.T) {
 		t.Fatalf("Expected %v, but got %v", expected, got)
 	}
 }
+
+type hierarchyTestData struct {
+	Key      string
+	Database struct {
+		Host    string
+		Replica struct {
+			Host string
+			EU   struct {
+				Host string
+			} `ini:"eu"`
+		}
+		Backup struct {
+			Host string
+		}
+	}
+	Origin struct {
+		URL string
+	} `ini:"remote \"my.origin\""`
+}
+
+func TestDecodeHierarchy(t *testing.T) {
+	t.Parallel()
+	var got hierarchyTestData
+	if err := Decode(strings.NewReader(hierarchyContent), &got); err != nil {
+		t.Fatalf("Unexpected error decoding: %q", err.Error())
+	}
+
+	var expected hierarchyTestData
+	expected.Key = "global"
+	expected.Database.Host = "db"
+	expected.Database.Replica.Host = "replica"
+	expected.Database.Replica.EU.Host = "eu"
+	expected.Database.Backup.Host = "backup"
+	expected.Origin.URL = "example.com"
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %+v, but got %+v", expected, got)
+	}
+}
+
+func TestDecodeCaseInsensitive(t *testing.T) {
+	t.Parallel()
+	c := Config{
+		Global:        {"NAME": "app", "Max_Connections": "10", "debug": "true"},
+		"DATABASE":    {"host name": "db", "Host": "ignored"},
+		"database.EU": {"HOST": "eu"},
+		"database.eu": {"port": "5432"},
+		"Tagged":      {"KEY": "value"},
+		"exact":       {"key": "lower", "Key": "exact"},
+	}
+
+	var got struct {
+		Name           string
+		MaxConnections int
+		Debug          bool
+		Database       struct {
+			HostName string
+			EU       struct {
+				Host string
+				Port int
+			}
+		}
+		Tagged struct {
+			Value string `ini:"key"`
+		} `ini:"tagged"`
+		Exact struct {
+			Key string
+		}
+	}
+	if err := c.Decode(&got); err != nil {
+		t.Fatalf("Unexpected error decoding: %s", err.Error())
+	}
+
+	if got.Name != "app" || got.MaxConnections != 10 || !got.Debug {
+		t.Fatalf("Unexpected global values: %+v", got)
+	} else if got.Database.HostName != "db" {
+		t.Fatalf("Expected the host name to be %q, but got %q", "db", got.Database.HostName)
+	} else if got.Database.EU.Host != "eu" || got.Database.EU.Port != 5432 {
+		t.Fatalf("Unexpected subsection values: %+v", got.Database.EU)
+	} else if got.Tagged.Value != "value" {
+		t.Fatalf("Expected the tagged value to be %q, but got %q", "value", got.Tagged.Value)
+	} else if got.Exact.Key != "exact" {
+		t.Fatalf("Expected the exact key to take precedence, but got %q", got.Exact.Key)
+	}
+}
+
+func TestDecodeKeyPrecedence(t *testing.T) {
+	t.Parallel()
+	type keyTestData struct {
+		Key   string
+		MyKey string
+	}
+	tests := []struct {
+		content  string
+		expected keyTestData
+	}{
+		// The lower case spelling takes precedence, in any order.
+		{"KEY=upper\nkey=lower", keyTestData{Key: "lower"}},
+		{"key=lower\nKEY=upper", keyTestData{Key: "lower"}},
+		{"Key=exact\nkey=lower", keyTestData{Key: "exact"}},
+		{"MY_KEY=upper\nMyKey=exact\nmykey=lower", keyTestData{MyKey: "lower"}},
+		{"MY_KEY=upper\nmy_key=lower", keyTestData{MyKey: "lower"}},
+		// Otherwise the first key in the file is used.
+		{"KEY=upper\nkEY=mixed", keyTestData{Key: "upper"}},
+		{"kEY=mixed\nKEY=upper", keyTestData{Key: "mixed"}},
+		{"MY-KEY=upper\nMy_kEY=mixed", keyTestData{MyKey: "upper"}},
+	}
+
+	for _, test := range tests {
+		var got keyTestData
+		if err := Decode(strings.NewReader(test.content), &got); err != nil {
+			t.Fatalf("Unexpected error decoding %q: %s", test.content, err.Error())
+		} else if got != test.expected {
+			t.Fatalf("Expected %q to decode into %+v, but got %+v", test.content, test.expected, got)
+		}
+
+		// A Config gives the same result, unless it can't know which key is
+		// first.
+		c, err := Parse(strings.NewReader(test.content))
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %s", test.content, err.Error())
+		}
+		got = keyTestData{}
+		if err := c.Decode(&got); err != nil {
+			if !strings.Contains(err.Error(), "both match it case-insensitively") {
+				t.Fatalf("Unexpected error decoding %q: %s", test.content, err.Error())
+			}
+		} else if got != test.expected {
+			t.Fatalf("Expected %q to decode into %+v, but got %+v", test.content, test.expected, got)
+		}
+	}
+
+	c := Config{Global: {"KEY": "upper", "kEY": "mixed"}}
+	var got keyTestData
+	expected := `ini: can't decode Key, keys "KEY" and "kEY" in section "global" both match it case-insensitively`
+	if err := c.Decode(&got); err == nil || err.Error() != expected {
+		t.Fatalf("Expected the error %q, but got %v", expected, err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import "strconv"
+
+// Dialect is a preset of Options that matches the ini format of a specific
+// tool, so that its files can be read and, using a Document, written.
+//
+//	config, err := ini.ParseWithOptions(r, ini.SystemdDialect.Options())
+//
+// The options can be changed before parsing, e.g. to set the limits. All
+// dialects, except for DefaultDialect and DesktopEntryDialect, decode "yes",
+// "no", "on" and "off" as booleans, see Options.ExtendedBools.
+type Dialect uint8
+
+// The supported dialects.
+const (
+	// DefaultDialect is the format that Parse accepts, i.e. the zero value of
+	// Options.
+	DefaultDialect Dialect = iota
+
+	// PythonDialect is the format of Python's configparser, with its default
+	// settings: "=" and ":" as separators, only full line comments, no
+	// quoting, values continued on indented lines, case-insensitive keys and
+	// the "DEFAULT" section as default section. Use Config.Interpolate with
+	// BasicInterpolation to interpolate the values.
+	PythonDialect
+
+	// PHPDialect is the format of PHP's parse_ini_file, e.g. php.ini: only ";"
+	// starts a comment, no continuation lines, quoted values and repeated
+	// keys, e.g. "extension[] = ...", are collected.
+	PHPDialect
+
+	// GitDialect is the format of git-config: quoted values, backslash
+	// continuation, keys without a value, which are true, repeated keys are
+	// collected, case-insensitive section names and keys, and sections such
+	// as `[remote "origin"]`, see SplitSection, of which the subsection is
+	// case-sensitive. Indentation has no meaning.
+	GitDialect
+
+	// SystemdDialect is the format of systemd unit files: only full line
+	// comments, no quoting, backslash continuation and repeated keys, e.g.
+	// "ExecStartPre=", are collected.
+	SystemdDialect
+
+	// SambaDialect is the format of Samba's smb.conf: only full line comments,
+	// no quoting, backslash continuation, case-insensitive section names and
+	// keys, and repeated keys override earlier keys. Indentation has no
+	// meaning.
+	SambaDialect
+
+	// DesktopEntryDialect is the format of XDG desktop entry files: only "#"
+	// starts a comment, only full line comments, no quoting and no
+	// continuation lines. Localized keys, e.g. "Name[de]", are separate keys.
+	DesktopEntryDialect
+)
+
+// String returns the name of the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case DefaultDialect:
+		return "default"
+	case PythonDialect:
+		return "Python"
+	case PHPDialect:
+		return "PHP"
+	case GitDialect:
+		return "git"
+	case SystemdDialect:
+		return "systemd"
+	case SambaDialect:
+		return "Samba"
+	case DesktopEntryDialect:
+		return "desktop entry"
+	default:
+		return "Dialect(" + strconv.Itoa(int(d)) + ")"
+	}
+}
+
+// Options returns the options for the dialect. An unknown dialect returns the
+// zero value, i.e. DefaultDialect.
+func (d Dialect) Options() Options {
+	switch d {
+	case PythonDialect:
+		return Options{
+			Separators:           "=:",
+			CommentChars:         "#;",
+			NoInlineComments:     true,
+			NoQuotes:             true,
+			IndentedContinuation: true,
+			SpaceAroundSeparator: true,
+			CaseInsensitiveKeys:  true,
+			DefaultSection:       "DEFAULT",
+			ExtendedBools:        true,
+		}
+	case PHPDialect:
+		return Options{
+			CommentChars:         ";",
+			SpaceAroundSeparator: true,
+			DuplicateSections:    SectionMerge,
+			DuplicateKeys:        KeyCollectAll,
+			ExtendedBools:        true,
+		}
+	case GitDialect:
+		return Options{
+			CommentChars:            "#;",
+			BackslashContinuation:   true,
+			AllowNoValue:            true,
+			NoValue:                 "true",
+			SpaceAroundSeparator:    true,
+			CaseInsensitiveSections: true,
+			CaseInsensitiveKeys:     true,
+			DuplicateSections:       SectionMerge,
+			DuplicateKeys:           KeyCollectAll,
+			ExtendedBools:           true,
+		}
+	case SystemdDialect:
+		return Options{
+			CommentChars:          "#;",
+			NoInlineComments:      true,
+			NoQuotes:              true,
+			BackslashContinuation: true,
+			DuplicateSections:     SectionMerge,
+			DuplicateKeys:         KeyCollectAll,
+			ExtendedBools:         true,
+		}
+	case SambaDialect:
+		return Options{
+			CommentChars:            ";#",
+			NoInlineComments:        true,
+			NoQuotes:                true,
+			BackslashContinuation:   true,
+			SpaceAroundSeparator:    true,
+			CaseInsensitiveSections: true,
+			CaseInsensitiveKeys:     true,
+			DuplicateSections:       SectionMerge,
+			DuplicateKeys:           KeyLastWins,
+			ExtendedBools:           true,
+		}
+	case DesktopEntryDialect:
+		return Options{
+			CommentChars:     "#",
+			NoInlineComments: true,
+			NoQuotes:         true,
+		}
+	default:
+		return Options{}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The directories in testdata/dialects, holding sample files of the dialects.
+// Every sample file has a JSON file with the expected configuration.
+var dialectDirs = map[string]Dialect{
+	"python":  PythonDialect,
+	"php":     PHPDialect,
+	"git":     GitDialect,
+	"systemd": SystemdDialect,
+	"samba":   SambaDialect,
+	"desktop": DesktopEntryDialect,
+}
+
+func TestDialectCorpus(t *testing.T) {
+	t.Parallel()
+	for dir, dialect := range dialectDirs {
+		paths, err := filepath.Glob(filepath.Join("testdata", "dialects", dir, "*"))
+		if err != nil {
+			t.Fatalf("Unexpected error listing samples: %s", err.Error())
+		}
+
+		var n int
+		for _, path := range paths {
+			if strings.HasSuffix(path, ".json") {
+				continue
+			}
+			n++
+			testDialectSample(t, dialect, path)
+		}
+		if n == 0 {
+			t.Fatalf("Expected samples of the %s dialect in %s", dialect, dir)
+		}
+	}
+}
+
+func testDialectSample(t *testing.T, dialect Dialect, path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error reading sample: %s", err.Error())
+	}
+	expectedJSON, err := os.ReadFile(path + ".json")
+	if err != nil {
+		t.Fatalf("Unexpected error reading expected configuration: %s", err.Error())
+	}
+	var expected Config
+	if err := json.Unmarshal(expectedJSON, &expected); err != nil {
+		t.Fatalf("Unexpected error decoding %s.json: %s", path, err.Error())
+	}
+
+	opts := dialect.Options()
+	got, err := ParseWithOptions(bytes.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing %s: %s", path, err.Error())
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected parsing %s to return %v, but got %v", path, expected, got)
+	}
+
+	doc, err := ParseDocumentWithOptions(bytes.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document %s: %s", path, err.Error())
+	} else if got := doc.String(); got != string(content) {
+		t.Fatalf("Expected Document.String() of %s to return %q, but got %q",
+			path, content, got)
+	}
+
+	// Changes written in the dialect must be read back by the same dialect.
+	// The default section is left alone, as its keys are inherited.
+	for section, keys := range expected {
+		if section == opts.DefaultSection || len(keys) == 0 {
+			continue
+		}
+		key := getSectionKeysAlpha(keys)[0]
+		setValue(t, doc, section, key, "changed value")
+		expected[section][key] = "changed value"
+	}
+	setValue(t, doc, "new section", "new key", "new value")
+	expected["new section"] = Section{"new key": "new value"}
+	if opts.DefaultSection != "" {
+		for key, value := range expected[opts.DefaultSection] {
+			if _, ok := expected["new section"][key]; !ok {
+				expected["new section"][key] = value
+			}
+		}
+	}
+
+	got, err = ParseWithOptions(strings.NewReader(doc.String()), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing changed %s: %s", path, err.Error())
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected parsing changed %s to return %v, but got %v",
+			path, expected, got)
+	}
+}
+
+func TestDialectValues(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		dialect      Dialect
+		path         string
+		section, key string
+		expected     []string
+	}{
+		{GitDialect, "git/config", `remote "origin"`, "fetch",
+			[]string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}},
+		{SystemdDialect, "systemd/override.conf", "Service", "ExecStart",
+			[]string{"", "/usr/local/bin/app --config /etc/app/app.ini --verbose"}},
+		{PHPDialect, "php/php.ini", "PHP", "extension[]", []string{"openssl", "pdo_mysql"}},
+	}
+
+	for _, test := range tests {
+		f, err := os.Open(filepath.Join("testdata", "dialects", test.path))
+		if err != nil {
+			t.Fatalf("Unexpected error opening sample: %s", err.Error())
+		}
+		doc, err := ParseDocumentWithOptions(f, test.dialect.Options())
+		f.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %s", test.path, err.Error())
+		} else if got := doc.Values(test.section, test.key); !reflect.DeepEqual(got, test.expected) {
+			t.Fatalf("Expected the values of %q in %s to be %q, but got %q",
+				test.key, test.path, test.expected, got)
+		}
+	}
+}
+
+func TestDialectDecode(t *testing.T) {
+	t.Parallel()
+	parse := func(dialect Dialect, name string) *Metadata {
+		f, err := os.Open(filepath.Join("testdata", "dialects", name))
+		if err != nil {
+			t.Fatalf("Unexpected error opening sample: %s", err.Error())
+		}
+		defer f.Close()
+		_, meta, err := ParseWithMetadata(f, dialect.Options())
+		if err != nil {
+			t.Fatalf("Unexpected error parsing sample: %s", err.Error())
+		}
+		return meta
+	}
+
+	var git struct {
+		Core struct {
+			Bare       bool
+			FileMode   bool
+			IgnoreCase bool
+		}
+	}
+	if err := parse(GitDialect, "git/config").Decode(&git); err != nil {
+		t.Fatalf("Unexpected error decoding sample: %s", err.Error())
+	} else if git.Core.Bare || !git.Core.FileMode || !git.Core.IgnoreCase {
+		t.Fatalf("Expected only bare to be false, but got %+v", git.Core)
+	}
+
+	var samba struct {
+		Homes struct {
+			Browseable bool
+			ReadOnly   bool `ini:"read only"`
+		}
+		Printers struct {
+			Printable bool
+			GuestOK   bool `ini:"guest ok"`
+		}
+	}
+	if err := parse(SambaDialect, "samba/smb.conf").Decode(&samba); err != nil {
+		t.Fatalf("Unexpected error decoding sample: %s", err.Error())
+	} else if samba.Homes.Browseable || samba.Homes.ReadOnly ||
+		!samba.Printers.Printable || samba.Printers.GuestOK {
+		t.Fatalf("Unexpected values %+v", samba)
+	}
+}
+
+func TestDialectWrite(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DefaultDialect, "[section]\nkey=value\n"},
+		{PythonDialect, "[section]\nkey = value\n"},
+		{GitDialect, "[section]\nkey = value\n"},
+		{SystemdDialect, "[section]\nkey=value\n"},
+		{DesktopEntryDialect, "[section]\nkey=value\n"},
+	}
+
+	for _, test := range tests {
+		doc, err := ParseDocumentWithOptions(strings.NewReader(""), test.dialect.Options())
+		if err != nil {
+			t.Fatalf("Unexpected error parsing: %s", err.Error())
+		}
+		setValue(t, doc, "section", "key", "value")
+		if got := doc.String(); got != test.expected {
+			t.Fatalf("Expected the %s dialect to write %q, but got %q",
+				test.dialect, test.expected, got)
+		}
+	}
+}
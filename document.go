@@ -0,0 +1,820 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NodeKind is the kind of line a Node represents.
+type NodeKind uint8
+
+// The different kinds of nodes.
+const (
+	BlankNode NodeKind = iota
+	CommentNode
+	SectionNode
+	KeyValueNode
+	IncludeNode
+	// ErrorNode is a line with a syntax error, only found in documents parsed
+	// in recovery mode, see Options.Recover.
+	ErrorNode
+)
+
+// String returns the name of the kind.
+func (k NodeKind) String() string {
+	switch k {
+	case BlankNode:
+		return "blank"
+	case CommentNode:
+		return "comment"
+	case SectionNode:
+		return "section"
+	case KeyValueNode:
+		return "key-value"
+	case IncludeNode:
+		return "include"
+	case ErrorNode:
+		return "error"
+	default:
+		return "NodeKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Node is a single line in a Document.
+type Node struct {
+	Kind NodeKind
+
+	// Section is the name of the section of a section node, or the name of the
+	// section the key-value pair is in for key-value nodes.
+	Section string
+
+	// Parent is the name of the section that a section node extends, see
+	// Options.InheritSections.
+	Parent string
+
+	// Key and Value are only set for key-value nodes. For include nodes Key
+	// holds the directive, e.g. "include" or "!includedir", and Value the path
+	// as written.
+	Key   string
+	Value string
+
+	// Comment holds the text of a comment line, or the inline comment of a
+	// section or key-value line, including the comment character.
+	Comment string
+
+	// File is the name of the file the line is read from, only set when
+	// parsing with ParseDocumentFS. Line is the line number in that file, or
+	// in the source, starting at one. Column is the position of the first
+	// non-whitespace character in the line, in runes starting at one, or zero
+	// for blank lines. All are empty for nodes added after parsing, see
+	// Position.
+	File   string
+	Line   int
+	Column int
+
+	// The line as it's written, including the line ending.
+	raw []byte
+	// Whether or not the line is read from an included file, see
+	// ParseDocumentFS.
+	included bool
+	// Indices of the value, including any quotes, in raw.
+	valueStart, valueEnd int
+}
+
+// Document is an ini formatted file that, unlike Config, keeps the comments,
+// blank lines, the order of sections and keys, and the way the values are
+// written. This allows a file to be read, changed and written back without
+// losing anything but the changes.
+//
+//	doc, err := ini.ParseDocument(f)
+//	if err != nil {
+//		// Handle error.
+//	}
+//	if err := doc.Set("http", "port", "8080"); err != nil {
+//		// Handle error.
+//	}
+//	_, err = doc.WriteTo(f2)
+//
+// Lines that aren't changed are written back byte for byte. The zero value is
+// an empty document.
+type Document struct {
+	nodes []*Node
+	// Options used in parsing, used to format new lines.
+	opts *Options
+	// Encoding of the input, used in writing.
+	encoding Encoding
+}
+
+// ParseDocument parses ini formatted input into a Document. The same rules as
+// Parse apply.
+func ParseDocument(r io.Reader) (*Document, error) {
+	return ParseDocumentWithOptions(r, Options{})
+}
+
+// Options returns the options used to format new lines.
+func (d *Document) options() *Options {
+	if d.opts == nil {
+		opts, _ := Options{}.withDefaults()
+		d.opts = &opts
+	}
+	return d.opts
+}
+
+// Nodes returns a copy of all nodes in the document, in order.
+func (d *Document) Nodes() []Node {
+	nodes := make([]Node, len(d.nodes))
+	for i, n := range d.nodes {
+		nodes[i] = *n
+	}
+	return nodes
+}
+
+// Config returns the key-value pairs in the document as a Config.
+func (d *Document) Config() Config {
+	o := d.options()
+	c := Config{Global: {}}
+	// Keys in Config by their folded key, see Options.CaseInsensitiveKeys.
+	keys := map[string]map[string]string{Global: {}}
+	for _, n := range d.nodes {
+		switch n.Kind {
+		case SectionNode:
+			if _, ok := c[n.Section]; !ok {
+				c[n.Section] = Section{}
+				keys[n.Section] = map[string]string{}
+			}
+		case KeyValueNode:
+			key, ok := keys[n.Section][o.foldKey(n.Key)]
+			if ok && o.DuplicateKeys == KeyFirstWins {
+				continue
+			} else if !ok {
+				key = n.Key
+				keys[n.Section][o.foldKey(key)] = key
+			}
+			c[n.Section][key] = n.Value
+		}
+	}
+	o.inheritKeys(c, d.parents(), d.sectionName(o.DefaultSection))
+	return c
+}
+
+// Get returns the value of the key in the section, much like
+// `config[section][key]`. If the key is used more than once the value is
+// picked based on the duplicate keys policy in the options. If the section
+// doesn't define the key it may be inherited, see Options.DefaultSection and
+// Options.InheritSections. The section and key are looked up in any case if
+// they're case-insensitive in the options, see
+// Options.CaseInsensitiveSections.
+func (d *Document) Get(section, key string) (string, bool) {
+	if n := d.lookup(section, key); n != nil {
+		return n.Value, true
+	}
+	return "", false
+}
+
+// Values returns all values of the key in the section, in order. Only with
+// the KeyCollectAll policy can a key have multiple values, with any other
+// policy it returns the value returned by Get.
+//
+//	; With KeyCollectAll.
+//	server = a
+//	server = b
+//
+// Results in []string{"a", "b"}. Like Get the values may be inherited.
+func (d *Document) Values(section, key string) []string {
+	for _, section := range d.lookupChain(section) {
+		if values := d.ownValues(section, key); values != nil {
+			return values
+		}
+	}
+	return nil
+}
+
+// OwnValues returns the values of the key defined in the section itself, see
+// Values.
+func (d *Document) ownValues(section, key string) []string {
+	if d.options().DuplicateKeys != KeyCollectAll {
+		if n := d.keyValue(section, key); n != nil {
+			return []string{n.Value}
+		}
+		return nil
+	}
+
+	var values []string
+	for _, n := range d.nodes {
+		if n.Kind == KeyValueNode && n.Section == section && d.options().sameKey(n.Key, key) {
+			values = append(values, n.Value)
+		}
+	}
+	return values
+}
+
+// Position returns the position in the source of the key in the section, as
+// returned by Get. It returns false if the key doesn't exist. The position is
+// the zero value for keys added after parsing.
+//
+//	if pos, ok := doc.Position("http", "port"); ok {
+//		log.Printf("%s: invalid port", pos)
+//	}
+//
+// Config is a plain map and doesn't hold any positions, use the Metadata
+// returned by ParseWithMetadata or a Document if the positions are needed.
+// Decode, Document.Decode and Metadata.Decode include the position in a
+// DecodeError.
+func (d *Document) Position(section, key string) (Position, bool) {
+	if n := d.lookup(section, key); n != nil {
+		return n.Position(), true
+	}
+	return Position{}, false
+}
+
+// SectionPosition returns the position in the source of the first header of
+// the section. It returns false if the section has no header, which is always
+// the case for the global section.
+func (d *Document) SectionPosition(section string) (Position, bool) {
+	section = d.sectionName(section)
+	for _, n := range d.nodes {
+		if n.Kind == SectionNode && n.Section == section {
+			return n.Position(), true
+		}
+	}
+	return Position{}, false
+}
+
+// Sections returns the names of the sections in the document in the order in
+// which they first appear, starting with the global section. Unlike ranging
+// over a Config, which is a map, the order is always the same.
+//
+//	for _, section := range doc.Sections() {
+//		for _, key := range doc.Keys(section) {
+//			value, _ := doc.Get(section, key)
+//			// Use the key-value pair.
+//		}
+//	}
+func (d *Document) Sections() []string {
+	return d.sections()
+}
+
+// Keys returns the keys in the section in the order in which they first
+// appear, the same keys as in the section returned by Config. Keys inherited
+// from other sections follow the keys of the section itself, in order of
+// precedence, see Options.DefaultSection and Options.InheritSections.
+func (d *Document) Keys(section string) []string {
+	o := d.options()
+	var keys []string
+	seen := map[string]bool{}
+	for _, section := range d.lookupChain(section) {
+		for _, n := range d.nodes {
+			if n.Kind == KeyValueNode && n.Section == section && !seen[o.foldKey(n.Key)] {
+				seen[o.foldKey(n.Key)] = true
+				keys = append(keys, n.Key)
+			}
+		}
+	}
+	return keys
+}
+
+// Decode decodes the document into a struct, see Config.Decode. Unlike
+// Config.Decode it decodes all values of multi-valued keys, see Values, into
+// slices.
+func (d *Document) Decode(dst interface{}) error {
+	return decode(d, dst, "Document.Decode", d.options().ExtendedBools)
+}
+
+func (d *Document) sections() []string {
+	sections := []string{Global}
+	seen := map[string]bool{Global: true}
+	for _, n := range d.nodes {
+		if n.Kind == SectionNode && !seen[n.Section] {
+			seen[n.Section] = true
+			sections = append(sections, n.Section)
+		}
+	}
+	return sections
+}
+
+func (d *Document) hasKeys(section string) bool {
+	chain := d.lookupChain(section)
+	for _, n := range d.nodes {
+		if n.Kind == KeyValueNode && contains(chain, n.Section) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Document) keys(section string) []string {
+	return d.Keys(section)
+}
+
+func (d *Document) ordered() bool {
+	return true
+}
+
+func (d *Document) values(section, key string) []string {
+	return d.Values(section, key)
+}
+
+func (d *Document) position(section, key string) Position {
+	pos, _ := d.Position(section, key)
+	return pos
+}
+
+// Set sets the value of the key in the section. If the key already exists the
+// value is replaced in place, keeping the rest of the line intact. If the key
+// is used more than once only the value returned by Get is replaced. Otherwise
+// the key is added after the last key in the section, creating the section if
+// needed.
+//
+// It returns an error, without changing the document, if the key-value pair
+// can't be written so that it's parsed back into the same key and value using
+// the options of the document. E.g. an empty key, or a value with a new line
+// if quotes are disabled, see Options.NoQuotes. The same goes for a key read
+// from an included file, see ParseDocumentFS, as only the lines of the file
+// itself are written.
+func (d *Document) Set(section, key, value string) error {
+	section = d.sectionName(section)
+	if n := d.keyValue(section, key); n != nil {
+		if err := d.options().checkKeyValue(section, key, value); err != nil {
+			return err
+		} else if n.included {
+			return includedKeyError("change", n)
+		}
+
+		n.Value = value
+		if n.valueStart < 0 {
+			// The value spans multiple lines, so we replace the entire line.
+			n.setRaw(d.options().formatKey(key) + d.options().separator() +
+				d.options().formatValue(value))
+			return nil
+		}
+
+		formatted := d.options().formatValue(value)
+		raw := make([]byte, 0, len(n.raw)-(n.valueEnd-n.valueStart)+len(formatted))
+		raw = append(raw, n.raw[:n.valueStart]...)
+		raw = append(raw, formatted...)
+		raw = append(raw, n.raw[n.valueEnd:]...)
+		n.raw = raw
+		n.valueEnd = n.valueStart + len(formatted)
+		return nil
+	}
+
+	return d.Add(section, key, value)
+}
+
+// Add adds a key-value pair to the section, after the last key in the
+// section. If the key already exists it's added again only if the duplicate
+// keys policy uses the new value, KeyLastWins or KeyCollectAll, which allows
+// multi-valued keys to be created, see Values. Like Set it returns an error if
+// the key-value pair, or the header of a new section, can't be written.
+func (d *Document) Add(section, key, value string) error {
+	section = d.sectionName(section)
+	if err := d.options().checkKeyValue(section, key, value); err != nil {
+		return err
+	} else if err := d.options().checkSection(section); err != nil {
+		return err
+	} else if err := d.checkRepeat(section, key); err != nil {
+		return err
+	} else if err := d.checkIncludedSection(section); err != nil {
+		return err
+	}
+
+	i := d.insertIndex(section)
+	var indent []byte
+	if i > 0 && d.nodes[i-1].Kind == KeyValueNode {
+		prev := d.nodes[i-1].raw
+		indent = prev[:len(prev)-len(bytes.TrimLeftFunc(prev, unicode.IsSpace))]
+	}
+
+	line := string(indent) + d.options().formatKey(key) + d.options().separator()
+	n := &Node{
+		Kind:       KeyValueNode,
+		Section:    section,
+		Key:        key,
+		Value:      value,
+		valueStart: len(line),
+	}
+	line += d.options().formatValue(value)
+	n.valueEnd = len(line)
+	n.raw = append([]byte(line), d.LineEnding().bytes()...)
+	d.insert(i, n)
+	return nil
+}
+
+// CheckRepeat checks if the key can be added to the section once more, see
+// Options.DuplicateKeys.
+func (d *Document) checkRepeat(section, key string) error {
+	policy := d.options().DuplicateKeys
+	if policy == KeyLastWins || policy == KeyCollectAll || d.keyValue(section, key) == nil {
+		return nil
+	}
+
+	if section == Global {
+		section = globalName
+	}
+	if policy == KeyFirstWins {
+		return fmt.Errorf("ini: key %q already exists in section %q and the options of the document ignore repeated keys",
+			key, section)
+	}
+	return fmt.Errorf("ini: key %q already exists in section %q and the options of the document don't allow repeated keys",
+		key, section)
+}
+
+// CheckIncludedSection checks if a new key can be added to the section, which
+// isn't the case if the section only exists in included files and a new
+// header would repeat it while duplicate sections aren't allowed.
+func (d *Document) checkIncludedSection(section string) error {
+	if section == Global || d.options().DuplicateSections == SectionMerge {
+		return nil
+	}
+
+	var included *Node
+	for _, n := range d.nodes {
+		if n.Kind == SectionNode && n.Section == section {
+			if !n.included {
+				return nil
+			}
+			included = n
+		}
+	}
+	if included != nil {
+		return fmt.Errorf("ini: can't add a key to section %q, it's read from included file %q",
+			section, included.File)
+	}
+	return nil
+}
+
+// IncludedKeyError returns the error for changing the key-value node, read
+// from an included file, with the action, e.g. "change".
+func includedKeyError(action string, n *Node) error {
+	section := n.Section
+	if section == Global {
+		section = globalName
+	}
+	return fmt.Errorf("ini: can't %s key %q in section %q, it's read from included file %q",
+		action, n.Key, section, n.File)
+}
+
+// Position returns the position of the node in the source, or the zero
+// Position if the node is added after parsing.
+func (n *Node) Position() Position {
+	return Position{File: n.File, Line: n.Line, Column: n.Column}
+}
+
+// SetRaw replaces the raw line with line, keeping the indentation, inline
+// comment and line ending of the old line.
+func (n *Node) setRaw(line string) {
+	indent := n.raw[:indentation(n.raw)]
+	ending := n.raw[len(bytes.TrimRight(n.raw, "\r\n")):]
+	if n.Comment != "" {
+		line += " " + n.Comment
+	}
+
+	raw := make([]byte, 0, len(indent)+len(line)+len(ending))
+	raw = append(raw, indent...)
+	raw = append(raw, line...)
+	raw = append(raw, ending...)
+	n.raw = raw
+	n.valueStart, n.valueEnd = -1, -1
+}
+
+// Delete removes all key-value pairs with the key from the section. It
+// returns false if the key wasn't found. It returns an error, without changing
+// the document, if any of the key-value pairs is read from an included file,
+// see ParseDocumentFS.
+func (d *Document) Delete(section, key string) (bool, error) {
+	section = d.sectionName(section)
+	var found bool
+	nodes := make([]*Node, 0, len(d.nodes))
+	for _, n := range d.nodes {
+		if n.Kind == KeyValueNode && n.Section == section && d.options().sameKey(n.Key, key) {
+			if n.included {
+				return false, includedKeyError("delete", n)
+			}
+			found = true
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	d.nodes = nodes
+	return found, nil
+}
+
+// DeleteSection removes the section header, the comments directly above it
+// and every line up to the next section. It returns false if the section
+// wasn't found. Deleting the global section removes all its key-value pairs.
+//
+// It returns an error, without changing the document, if any of the lines to
+// remove is read from an included file or is an include directive, see
+// ParseDocumentFS.
+func (d *Document) DeleteSection(section string) (bool, error) {
+	section = d.sectionName(section)
+	var found, inSection bool
+	keep := make([]bool, len(d.nodes))
+	for i, n := range d.nodes {
+		if n.Kind == SectionNode {
+			inSection = n.Section == section
+			// Comments directly above a section header belong to that section.
+			for j := i - 1; j >= 0 && d.nodes[j].Kind == CommentNode; j-- {
+				keep[j] = !inSection
+			}
+		}
+
+		if inSection || (n.Kind == KeyValueNode && n.Section == section) {
+			found = true
+			continue
+		}
+		keep[i] = true
+	}
+
+	nodes := make([]*Node, 0, len(d.nodes))
+	for i, n := range d.nodes {
+		if keep[i] {
+			nodes = append(nodes, n)
+		} else if n.included || n.Kind == IncludeNode {
+			if section == Global {
+				section = globalName
+			}
+			return false, fmt.Errorf("ini: can't delete section %q, it has lines from included files", section)
+		}
+	}
+	d.nodes = nodes
+	return found, nil
+}
+
+// String returns the ini formatted document.
+func (d *Document) String() string {
+	return d.buffer().String()
+}
+
+// Bytes returns the ini formatted document.
+func (d *Document) Bytes() []byte {
+	return d.buffer().Bytes()
+}
+
+// WriteTo writes the document to the writer in the ini format, in the same
+// encoding as it was read, see Encoding. Every line keeps its own line ending,
+// see LineEnding.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	output, err := encode(d.buffer().Bytes(), d.encoding)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(output)
+	return int64(n), err
+}
+
+// Encoding returns the character encoding of the input, as detected or set in
+// the options. WriteTo writes the document in the same encoding, while String
+// and Bytes always return UTF-8 without a byte order mark.
+func (d *Document) Encoding() Encoding {
+	return d.encoding
+}
+
+// Buffer creates a `bytes.Buffer` with the ini formatted document.
+func (d *Document) buffer() *bytes.Buffer {
+	var result bytes.Buffer
+	for _, n := range d.nodes {
+		if !n.included {
+			result.Write(n.raw)
+		}
+	}
+	return &result
+}
+
+// Parents returns the parents of the sections, see Options.InheritSections.
+func (d *Document) parents() map[string]string {
+	parents := map[string]string{}
+	for _, n := range d.nodes {
+		if n.Kind == SectionNode && n.Parent != "" {
+			parents[n.Section] = d.sectionName(n.Parent)
+		}
+	}
+	return parents
+}
+
+// LookupChain returns the sections in which keys of the section are looked
+// up, see lookupChain.
+func (d *Document) lookupChain(section string) []string {
+	return lookupChain(d.parents(), d.sectionName(d.options().DefaultSection),
+		d.sectionName(section))
+}
+
+// SectionName returns the name of the section as used in the nodes, which
+// differs from name if section names are case-insensitive and the section is
+// written in another case.
+func (d *Document) sectionName(name string) string {
+	o := d.options()
+	if !o.CaseInsensitiveSections {
+		return name
+	}
+	folded := o.foldSection(name)
+	for _, n := range d.nodes {
+		if (n.Kind == SectionNode || n.Kind == KeyValueNode) && o.foldSection(n.Section) == folded {
+			return n.Section
+		}
+	}
+	return name
+}
+
+// Lookup returns the key-value node of the key, as returned by Get, or nil if
+// the key doesn't exist.
+func (d *Document) lookup(section, key string) *Node {
+	for _, section := range d.lookupChain(section) {
+		if n := d.keyValue(section, key); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// KeyValue returns the key-value node with the key in the section, or nil if
+// there is none. If there are multiple nodes the first is returned if the
+// first key wins, otherwise the last.
+func (d *Document) keyValue(section, key string) *Node {
+	var found *Node
+	for _, n := range d.nodes {
+		if n.Kind == KeyValueNode && n.Section == section && d.options().sameKey(n.Key, key) {
+			if d.options().DuplicateKeys == KeyFirstWins {
+				return n
+			}
+			found = n
+		}
+	}
+	return found
+}
+
+// InsertIndex returns the index at which a new key in the section should be
+// inserted. If the section doesn't exist yet it's added to the end of the
+// document.
+func (d *Document) insertIndex(section string) int {
+	index := -1
+	for i, n := range d.nodes {
+		// Only the lines of the file itself are written.
+		if n.Section != section || n.included {
+			continue
+		}
+		if n.Kind == KeyValueNode || (n.Kind == SectionNode && index == -1) {
+			index = i + 1
+		}
+	}
+	if index != -1 {
+		return index
+	}
+
+	if section == Global {
+		// Add the key before the first section, but keep any comments directly
+		// above the section with the section.
+		for i, n := range d.nodes {
+			if n.Kind == SectionNode && !n.included {
+				for i > 0 && d.nodes[i-1].Kind == CommentNode && !d.nodes[i-1].included {
+					i--
+				}
+				return i
+			}
+		}
+		return len(d.nodes)
+	}
+
+	ending := d.LineEnding().bytes()
+	if len(d.nodes) != 0 {
+		d.insert(len(d.nodes), &Node{Kind: BlankNode, raw: ending})
+	}
+	header := d.options().formatSection(section)
+	d.insert(len(d.nodes), &Node{
+		Kind:    SectionNode,
+		Section: section,
+		raw:     append([]byte(header), ending...),
+	})
+	return len(d.nodes)
+}
+
+// Insert inserts the node at index i, making sure the node before it ends with
+// a new line, see LineEnding.
+func (d *Document) insert(i int, n *Node) {
+	if i > 0 {
+		prev := d.nodes[i-1]
+		if len(prev.raw) != 0 && prev.raw[len(prev.raw)-1] != '\n' {
+			prev.raw = append(prev.raw, d.LineEnding().bytes()...)
+		}
+	}
+	d.nodes = append(d.nodes, nil)
+	copy(d.nodes[i+1:], d.nodes[i:])
+	d.nodes[i] = n
+}
+
+// Separator returns the separator written between new keys and values.
+func (o *Options) separator() string {
+	if o.SpaceAroundSeparator {
+		return " " + o.Separators[:1] + " "
+	}
+	return o.Separators[:1]
+}
+
+// FormatSection formats the header of a section so that it's parsed back into
+// the same section, only quoting the name if needed.
+func (o *Options) formatSection(name string) string {
+	header := string(sectionStart) + name + string(sectionEnd)
+	if o.NoQuotes {
+		return header
+	}
+	n, err := o.parseSection([]byte(header))
+	if err != nil || n.Section != name || n.Parent != "" || strings.ContainsRune(name, '\n') {
+		return string(sectionStart) + strconv.Quote(name) + string(sectionEnd)
+	}
+	return header
+}
+
+// CheckSection checks if the header of the section, see formatSection, is
+// parsed back into the same section.
+func (o *Options) checkSection(name string) error {
+	if name == Global {
+		return nil
+	}
+	header := o.formatSection(name)
+	n, err := o.parseSection([]byte(header))
+	if err != nil || n.Section != name || n.Parent != "" || strings.ContainsRune(header, '\n') {
+		return fmt.Errorf("ini: can't write section %q with the options of the document", name)
+	}
+	return nil
+}
+
+// CheckKeyValue checks if the key-value pair, formatted using formatKey and
+// formatValue, is parsed back into the same key and value.
+func (o *Options) checkKeyValue(section, key, value string) error {
+	if section == Global {
+		section = globalName
+	}
+	if key == "" {
+		return fmt.Errorf("ini: can't write an empty key in section %q", section)
+	}
+
+	line := o.formatKey(key) + o.separator() + o.formatValue(value)
+	n, err := o.parseLine([]byte(line))
+	if err != nil || n.Kind != KeyValueNode || n.Key != key || n.Value != value ||
+		strings.ContainsRune(line, '\n') || o.endsWithContinuation([]byte(line)) {
+		return fmt.Errorf("ini: can't write key %q with value %q in section %q with the options of the document",
+			key, value, section)
+	}
+	return nil
+}
+
+// FormatKey formats a key so that it's parsed back into the same key, only
+// quoting it if needed.
+func (o *Options) formatKey(key string) string {
+	if o.NoQuotes {
+		return key
+	}
+	if key == "" || o.needsQuoting(key) || strings.ContainsAny(key, o.Separators) ||
+		key[0] == sectionStart {
+		return strconv.Quote(key)
+	}
+	return key
+}
+
+// FormatValue formats a value so that it's parsed back into the same value,
+// only quoting it if needed.
+func (o *Options) formatValue(value string) string {
+	if !o.NoQuotes && o.needsQuoting(value) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// NeedsQuoting checks if a key or value needs to be quoted to be parsed back
+// into the same string.
+func (o *Options) needsQuoting(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	first, _ := utf8.DecodeRuneInString(s)
+	last, _ := utf8.DecodeLastRuneInString(s)
+	if unicode.IsSpace(first) || unicode.IsSpace(last) {
+		return true
+	}
+
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError, !unicode.IsPrint(r) && r != ' ':
+			return true
+		case r < utf8.RuneSelf && o.isSpecial(byte(r)):
+			return true
+		}
+	}
+	return false
+}
+
+// IsSpecial checks if the byte has a special meaning in a key or value.
+func (o *Options) isSpecial(b byte) bool {
+	return o.isCommentStart(b) || b == doubleQuote || b == singleQuote ||
+		b == escape
+}
@@ -0,0 +1,603 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDocument = `; Configuration.
+msg="Welcome \"Bob\"" ; A welcome message
+name='http server' ;)
+
+; Database configuration.
+[database]
+	user = "bob" ; Maybe it's not specific enough.
+	password = password ; Don't tell the boss.
+
+# HTTP configuration.
+[http] ; Section comment.
+port=8080
+url=example.com`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []string{
+		"",
+		"\n\n",
+		"key=value",
+		"key=value\n",
+		"key=value\r\n[section]\r\n",
+		testDocument,
+		testDocument + "\n",
+		"key = first \\\n\tsecond\nkey2 = value\n",
+		"key = first\n\t; comment\n\tsecond\r\n\tthird\n[section]\n",
+	}
+
+	opts := Options{BackslashContinuation: true, IndentedContinuation: true}
+	for _, content := range tests {
+		doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+		if err != nil {
+			t.Fatalf("Unexpected error from ParseDocument(%q): %s", content, err.Error())
+		}
+
+		if got := doc.String(); got != content {
+			t.Fatalf("Expected Document.String() to return %q, but got %q",
+				content, got)
+		}
+	}
+}
+
+func TestDocumentConfig(t *testing.T) {
+	t.Parallel()
+	doc, err := ParseDocument(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	expected, err := Parse(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing config: %s", err.Error())
+	}
+
+	if got := doc.Config(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Document.Config() to return %v, but got %v",
+			expected, got)
+	}
+}
+
+func TestDocumentNodes(t *testing.T) {
+	t.Parallel()
+	content := "; comment\nkey = value ; inline\n\n[section] # comment"
+	doc, err := ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	expected := []Node{
+		{Kind: CommentNode, Comment: "; comment", Line: 1},
+		{Kind: KeyValueNode, Key: "key", Value: "value", Comment: "; inline", Line: 2},
+		{Kind: BlankNode, Line: 3},
+		{Kind: SectionNode, Section: "section", Comment: "# comment", Line: 4},
+	}
+
+	got := doc.Nodes()
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d nodes, but got %d", len(expected), len(got))
+	}
+	for i, n := range got {
+		e := expected[i]
+		if n.Kind != e.Kind || n.Section != e.Section || n.Key != e.Key ||
+			n.Value != e.Value || n.Comment != e.Comment || n.Line != e.Line {
+			t.Fatalf("Expected node %d to be %+v, but got %+v", i, e, n)
+		}
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		section, key, value string
+		expected            string
+	}{
+		{"database", "password", "secret", strings.Replace(testDocument,
+			"password = password ;", "password = secret ;", 1)},
+		{Global, "msg", "Hi", strings.Replace(testDocument,
+			`msg="Welcome \"Bob\"" ;`, "msg=Hi ;", 1)},
+		{"http", "url", " spaced ", strings.Replace(testDocument,
+			"url=example.com", `url=" spaced "`, 1)},
+		{"http", "url", "", strings.Replace(testDocument,
+			"url=example.com", "url=", 1)},
+		{"database", "host", "localhost", strings.Replace(testDocument,
+			"Don't tell the boss.\n", "Don't tell the boss.\n\thost=localhost\n", 1)},
+		{Global, "new", "value", strings.Replace(testDocument,
+			"name='http server' ;)\n", "name='http server' ;)\nnew=value\n", 1)},
+		{"http", "host", "example.com", testDocument + "\nhost=example.com\n"},
+		{"new", "k=y", "value", testDocument + "\n\n[new]\n\"k=y\"=value\n"},
+		{"a ] b", "key", "value", testDocument + "\n\n[\"a ] b\"]\nkey=value\n"},
+	}
+
+	for _, test := range tests {
+		doc, err := ParseDocument(strings.NewReader(testDocument))
+		if err != nil {
+			t.Fatalf("Unexpected error parsing document: %s", err.Error())
+		}
+
+		setValue(t, doc, test.section, test.key, test.value)
+		got := doc.String()
+		if got != test.expected {
+			t.Fatalf("Expected Document.Set(%q, %q, %q) to result in %q, but got %q",
+				test.section, test.key, test.value, test.expected, got)
+		}
+
+		c, err := Parse(strings.NewReader(got))
+		if err != nil {
+			t.Fatalf("Unexpected error parsing changed document: %s", err.Error())
+		}
+		if value := c[test.section][test.key]; value != test.value {
+			t.Fatalf("Expected the value to be %q after parsing, but got %q",
+				test.value, value)
+		}
+	}
+}
+
+// SetValue calls Document.Set, failing the test on an error.
+func setValue(t *testing.T, doc *Document, section, key, value string) {
+	t.Helper()
+	if err := doc.Set(section, key, value); err != nil {
+		t.Fatalf("Unexpected error setting key %q in section %q: %s", key, section, err.Error())
+	}
+}
+
+func TestDocumentSetError(t *testing.T) {
+	t.Parallel()
+	systemd := SystemdDialect.Options()
+	tests := []struct {
+		opts                Options
+		section, key, value string
+		expected            string
+	}{
+		{Options{}, "s", "", "v", `ini: can't write an empty key in section "s"`},
+		{Options{}, Global, "", "v", `ini: can't write an empty key in section "global"`},
+		{systemd, "Service", "B", "x\ny",
+			`ini: can't write key "B" with value "x\ny" in section "Service" with the options of the document`},
+		{systemd, "Service", "C=D", "z",
+			`ini: can't write key "C=D" with value "z" in section "Service" with the options of the document`},
+		{systemd, "Service", " B", "z",
+			`ini: can't write key " B" with value "z" in section "Service" with the options of the document`},
+		{Options{NoQuotes: true}, "Service", "B", "a ; b",
+			`ini: can't write key "B" with value "a ; b" in section "Service" with the options of the document`},
+		{systemd, "Service", "B", `C:\dir\`,
+			`ini: can't write key "B" with value "C:\\dir\\" in section "Service" with the options of the document`},
+		{systemd, "a ] b", "B", "z", `ini: can't write section "a ] b" with the options of the document`},
+	}
+
+	content := "[Service]\nB=value\n"
+	for _, test := range tests {
+		doc, err := ParseDocumentWithOptions(strings.NewReader(content), test.opts)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing document: %s", err.Error())
+		}
+
+		for _, fn := range []func(section, key, value string) error{doc.Set, doc.Add} {
+			if err := fn(test.section, test.key, test.value); err == nil || err.Error() != test.expected {
+				t.Fatalf("Expected the error %q, but got %v", test.expected, err)
+			} else if got := doc.String(); got != content {
+				t.Fatalf("Expected the document to be unchanged, but got %q", got)
+			}
+		}
+	}
+
+	// Adding an existing key is only allowed if the added value is used.
+	addTests := []struct {
+		opts     Options
+		key      string
+		expected string
+	}{
+		{Options{}, "B", `ini: key "B" already exists in section "Service" and the options of the document don't allow repeated keys`},
+		{Options{CaseInsensitiveKeys: true}, "b", `ini: key "b" already exists in section "Service" and the options of the document don't allow repeated keys`},
+		{Options{DuplicateKeys: KeyFirstWins}, "B", `ini: key "B" already exists in section "Service" and the options of the document ignore repeated keys`},
+		{Options{DuplicateKeys: KeyLastWins}, "B", ""},
+		{Options{DuplicateKeys: KeyCollectAll}, "B", ""},
+	}
+	for _, test := range addTests {
+		doc, err := ParseDocumentWithOptions(strings.NewReader(content), test.opts)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing document: %s", err.Error())
+		}
+
+		err = doc.Add("Service", test.key, "added")
+		if test.expected == "" {
+			if err != nil {
+				t.Fatalf("Unexpected error adding key: %s", err.Error())
+			} else if _, err := ParseWithOptions(strings.NewReader(doc.String()), test.opts); err != nil {
+				t.Fatalf("Unexpected error parsing changed document: %s", err.Error())
+			}
+		} else if err == nil || err.Error() != test.expected {
+			t.Fatalf("Expected the error %q, but got %v", test.expected, err)
+		} else if got := doc.String(); got != content {
+			t.Fatalf("Expected the document to be unchanged, but got %q", got)
+		}
+	}
+
+	// The same values can be written with quotes.
+	doc, err := ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+	setValue(t, doc, "Service", "B", "x\ny")
+	setValue(t, doc, "Service", "C=D", "z")
+	setValue(t, doc, "a ] b", "B", `C:\dir\`)
+	expected := "[Service]\nB=\"x\\ny\"\n\"C=D\"=z\n\n[\"a ] b\"]\nB=\"C:\\\\dir\\\\\"\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+	c, err := Parse(strings.NewReader(expected))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing changed document: %s", err.Error())
+	} else if got := c["a ] b"]["B"]; got != `C:\dir\` {
+		t.Fatalf("Expected the value to be %q, but got %q", `C:\dir\`, got)
+	}
+}
+
+func TestDocumentSetGlobalEmpty(t *testing.T) {
+	t.Parallel()
+	content := "; File comment.\n\n; Section comment.\n[section]\n"
+	doc, err := ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	setValue(t, doc, Global, "key", "value")
+	expected := "; File comment.\n\nkey=value\n; Section comment.\n[section]\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	t.Parallel()
+	doc, err := ParseDocument(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	if found, err := doc.Delete("database", "password"); err != nil || !found {
+		t.Fatalf("Expected Document.Delete to find the key, but got %v and %v", found, err)
+	} else if found, err := doc.Delete("database", "password"); err != nil || found {
+		t.Fatalf("Expected Document.Delete to not find the deleted key, but got %v and %v", found, err)
+	}
+	expected := strings.Replace(testDocument,
+		"\tpassword = password ; Don't tell the boss.\n", "", 1)
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+
+	if found, err := doc.DeleteSection("database"); err != nil || !found {
+		t.Fatalf("Expected Document.DeleteSection to find the section, but got %v and %v", found, err)
+	}
+	expected = strings.Replace(expected, "; Database configuration.\n[database]\n"+
+		"\tuser = \"bob\" ; Maybe it's not specific enough.\n\n", "", 1)
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+
+	if found, err := doc.DeleteSection(Global); err != nil || !found {
+		t.Fatalf("Expected Document.DeleteSection to find the global section, but got %v and %v", found, err)
+	}
+	if _, ok := doc.Get(Global, "msg"); ok {
+		t.Fatal("Expected the global keys to be deleted")
+	}
+	if value, _ := doc.Get("http", "port"); value != "8080" {
+		t.Fatalf("Expected other sections to remain, but got %q", value)
+	}
+}
+
+func TestDocumentSetMultiLine(t *testing.T) {
+	t.Parallel()
+	content := "[section]\n  key = first \\\n\tsecond ; comment\r\n" +
+		"  key2 = first\n    ; comment\n    second\n"
+	opts := Options{BackslashContinuation: true, IndentedContinuation: true}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	if value, _ := doc.Get("section", "key2"); value != "first\nsecond" {
+		t.Fatalf("Expected the value to be %q, but got %q", "first\nsecond", value)
+	}
+
+	setValue(t, doc, "section", "key", "value")
+	setValue(t, doc, "section", "key2", "value2")
+	expected := "[section]\n  key=value ; comment\r\n  key2=value2\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+}
+
+func TestDocumentValues(t *testing.T) {
+	t.Parallel()
+	content := "[Service]\nEnvironment=A=1\nExecStart=/bin/app\nEnvironment=B=2\n"
+	opts := Options{DuplicateKeys: KeyCollectAll}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	expected := []string{"A=1", "B=2"}
+	if got := doc.Values("Service", "Environment"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Document.Values to return %q, but got %q", expected, got)
+	}
+	if got := doc.Values("Service", "Unknown"); got != nil {
+		t.Fatalf("Expected Document.Values to return nil, but got %q", got)
+	}
+	if value, _ := doc.Get("Service", "Environment"); value != "B=2" {
+		t.Fatalf("Expected Document.Get to return the last value, but got %q", value)
+	}
+
+	if err := doc.Add("Service", "Environment", "C=3"); err != nil {
+		t.Fatalf("Unexpected error adding the value: %s", err.Error())
+	}
+	expected = append(expected, "C=3")
+	if got := doc.Values("Service", "Environment"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Document.Values to return %q, but got %q", expected, got)
+	}
+	expectedContent := content + "Environment=C=3\n"
+	if got := doc.String(); got != expectedContent {
+		t.Fatalf("Expected %q, but got %q", expectedContent, got)
+	}
+
+	// Without collecting all values only a single value is returned.
+	doc, err = ParseDocumentWithOptions(strings.NewReader(content),
+		Options{DuplicateKeys: KeyLastWins})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+	expected = []string{"B=2"}
+	if got := doc.Values("Service", "Environment"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Document.Values to return %q, but got %q", expected, got)
+	}
+}
+
+func TestDocumentCaseInsensitive(t *testing.T) {
+	t.Parallel()
+	content := "[DEFAULT]\nTimeout = 10\n[Base]\nHost = localhost\n" +
+		"[prod : BASE]\nPort = 80\n[PROD]\nport = 8080\n"
+	opts := Options{
+		CaseInsensitiveSections: true,
+		CaseInsensitiveKeys:     true,
+		DuplicateSections:       SectionMerge,
+		DuplicateKeys:           KeyCollectAll,
+		DefaultSection:          "default",
+		InheritSections:         true,
+	}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	tests := []struct {
+		section, key, expected string
+	}{
+		{"prod", "PORT", "8080"},
+		{"Prod", "host", "localhost"},
+		{"pRoD", "timeout", "10"},
+		{"base", "HOST", "localhost"},
+	}
+	for _, test := range tests {
+		if got, ok := doc.Get(test.section, test.key); !ok || got != test.expected {
+			t.Fatalf("Expected Document.Get(%q, %q) to return %q, but got %q",
+				test.section, test.key, test.expected, got)
+		}
+	}
+	expectedValues := []string{"80", "8080"}
+	if got := doc.Values("PROD", "port"); !reflect.DeepEqual(got, expectedValues) {
+		t.Fatalf("Expected Document.Values to return %q, but got %q", expectedValues, got)
+	}
+	if pos, ok := doc.SectionPosition("BASE"); !ok || pos.Line != 3 {
+		t.Fatalf("Expected the section to be on line 3, but got %s", pos)
+	}
+
+	expectedConfig := Config{
+		Global:    {},
+		"DEFAULT": {"Timeout": "10"},
+		"Base":    {"Timeout": "10", "Host": "localhost"},
+		"prod":    {"Timeout": "10", "Host": "localhost", "Port": "8080"},
+	}
+	if got := doc.Config(); !reflect.DeepEqual(got, expectedConfig) {
+		t.Fatalf("Expected Document.Config() to return %v, but got %v", expectedConfig, got)
+	}
+
+	// Changes keep the original spelling.
+	setValue(t, doc, "BASE", "host", "example.com")
+	setValue(t, doc, "base", "NEW", "value")
+	if found, err := doc.Delete("Prod", "PORT"); err != nil || !found {
+		t.Fatalf("Expected Document.Delete to find the key, but got %v and %v", found, err)
+	}
+	expected := "[DEFAULT]\nTimeout = 10\n[Base]\nHost = example.com\nNEW=value\n" +
+		"[prod : BASE]\n[PROD]\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+	if found, err := doc.DeleteSection("default"); err != nil || !found {
+		t.Fatalf("Expected to delete the default section, but got %v and %v", found, err)
+	}
+}
+
+type multiValueTestData struct {
+	Server  string
+	Servers []string `ini:"server"`
+	Ports   []int    `ini:"port"`
+	Section struct {
+		Key []string
+	}
+}
+
+func TestDocumentOrder(t *testing.T) {
+	t.Parallel()
+	content := "zone = eu\nname = app\n[plugins]\nzip = 1\nauth = 2\n" +
+		"[DEFAULT]\ntimeout = 10\n[cache : plugins]\nsize = 3\n[Plugins]\nAUTH = 4\nlog = 5\n"
+	opts := Options{
+		DuplicateSections:       SectionMerge,
+		DuplicateKeys:           KeyLastWins,
+		CaseInsensitiveSections: true,
+		CaseInsensitiveKeys:     true,
+		DefaultSection:          "DEFAULT",
+		InheritSections:         true,
+	}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	expectedSections := []string{Global, "plugins", "DEFAULT", "cache"}
+	if got := doc.Sections(); !reflect.DeepEqual(got, expectedSections) {
+		t.Fatalf("Expected Document.Sections() to return %q, but got %q", expectedSections, got)
+	}
+
+	tests := []struct {
+		section  string
+		expected []string
+	}{
+		{Global, []string{"zone", "name"}},
+		{"plugins", []string{"zip", "auth", "log", "timeout"}},
+		{"PLUGINS", []string{"zip", "auth", "log", "timeout"}},
+		{"DEFAULT", []string{"timeout"}},
+		{"cache", []string{"size", "zip", "auth", "log", "timeout"}},
+		{"unknown", []string{"timeout"}},
+	}
+
+	c := doc.Config()
+	for _, test := range tests {
+		got := doc.Keys(test.section)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Fatalf("Expected Document.Keys(%q) to return %q, but got %q",
+				test.section, test.expected, got)
+		}
+		if section, ok := c[test.section]; ok && len(section) != len(got) {
+			t.Fatalf("Expected Document.Keys(%q) to return the keys in %v, but got %q",
+				test.section, section, got)
+		}
+	}
+}
+
+func TestDocumentDecode(t *testing.T) {
+	t.Parallel()
+	content := "server=a\nserver=b, c\nport=80\nport=443\n[section]\nkey=/bin/a x,y\nkey=/bin/b"
+	opts := Options{DuplicateKeys: KeyCollectAll}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	var got multiValueTestData
+	if err := doc.Decode(&got); err != nil {
+		t.Fatalf("Unexpected error decoding document: %s", err.Error())
+	}
+
+	var expected multiValueTestData
+	expected.Server = "b, c"
+	expected.Servers = []string{"a", "b, c"}
+	expected.Ports = []int{80, 443}
+	expected.Section.Key = []string{"/bin/a x,y", "/bin/b"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, but got %v", expected, got)
+	}
+
+	if err := doc.Decode(got); err == nil {
+		t.Fatal("Expected an error decoding into a non-pointer")
+	} else if expected := "ini: Document.Decode requires a pointer to a struct"; err.Error() != expected {
+		t.Fatalf("Expected error %q, but got %q", expected, err.Error())
+	}
+
+	// A Config only holds the last value.
+	c := doc.Config()
+	got = multiValueTestData{}
+	if err := c.Decode(&got); err != nil {
+		t.Fatalf("Unexpected error decoding config: %s", err.Error())
+	} else if expected := []string{"/bin/b"}; !reflect.DeepEqual(got.Section.Key, expected) {
+		t.Fatalf("Expected Config.Decode to decode %q, but got %q", expected, got.Section.Key)
+	}
+
+	content = "port=80\nport=x\nport=443"
+	doc, err = ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+	var decErr DecodeError
+	if err := doc.Decode(&got); !errors.As(err, &decErr) {
+		t.Fatalf("Expected a DecodeError, but got %v", err)
+	} else if decErr.Key != "port" || decErr.Value != "x" {
+		t.Fatalf("Expected the error to be about the value %q of key %q, but got %q of %q",
+			"x", "port", decErr.Value, decErr.Key)
+	}
+}
+
+func TestDocumentPosition(t *testing.T) {
+	t.Parallel()
+	content := "key = value\n[database]\n  user = bob\n  \"пароль\" = secret\n" +
+		"  [other : database]\n"
+	opts := Options{InheritSections: true}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	keyTests := []struct {
+		section, key string
+		expected     Position
+		found        bool
+	}{
+		{Global, "key", Position{Line: 1, Column: 1}, true},
+		{"database", "user", Position{Line: 3, Column: 3}, true},
+		{"database", "пароль", Position{Line: 4, Column: 3}, true},
+		{"other", "user", Position{Line: 3, Column: 3}, true},
+		{"database", "unknown", Position{}, false},
+	}
+	for _, test := range keyTests {
+		pos, found := doc.Position(test.section, test.key)
+		if pos != test.expected || found != test.found {
+			t.Fatalf("Expected Document.Position(%q, %q) to return %v, %t, but got %v, %t",
+				test.section, test.key, test.expected, test.found, pos, found)
+		}
+	}
+
+	sectionTests := []struct {
+		section  string
+		expected Position
+		found    bool
+	}{
+		{Global, Position{}, false},
+		{"database", Position{Line: 2, Column: 1}, true},
+		{"other", Position{Line: 5, Column: 3}, true},
+		{"unknown", Position{}, false},
+	}
+	for _, test := range sectionTests {
+		pos, found := doc.SectionPosition(test.section)
+		if pos != test.expected || found != test.found {
+			t.Fatalf("Expected Document.SectionPosition(%q) to return %v, %t, but got %v, %t",
+				test.section, test.expected, test.found, pos, found)
+		}
+	}
+
+	setValue(t, doc, "database", "host", "localhost")
+	if pos, found := doc.Position("database", "host"); !found || pos.IsValid() {
+		t.Fatalf("Expected an unknown position for an added key, but got %v", pos)
+	}
+
+	doc, err = ParseDocumentFS(includeFS, "app.ini", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+	expected := Position{File: "tls.ini", Line: 2, Column: 1}
+	if pos, _ := doc.Position("tls", "cert"); pos != expected {
+		t.Fatalf("Expected the position to be %v, but got %v", expected, pos)
+	} else if got := pos.String(); got != "tls.ini:2:1" {
+		t.Fatalf("Expected the position to be formatted as %q, but got %q", "tls.ini:2:1", got)
+	}
+}
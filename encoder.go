@@ -0,0 +1,294 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoder writes configurations in the ini format to an output stream. The
+// zero value of the options writes a Config like Config.WriteTo, the options
+// allow it to look more like a hand-written file.
+//
+//	enc := ini.NewEncoder(f)
+//	enc.MinimalQuoting = true
+//	enc.SpaceAroundSeparator = true
+//	enc.Header = "Generated by app, do not edit."
+//	err := enc.Encode(config)
+//
+// Results in the following output, which Parse reads back into the same
+// configuration.
+//
+//	; Generated by app, do not edit.
+//
+//	name = app
+//
+//	[database]
+//	host = localhost
+//	user = "bob ; admin"
+//
+// The formatting options don't apply to a Document, which keeps its own
+// format.
+type Encoder struct {
+	// Encoding is the character encoding of the output, defaults to UTF-8.
+	Encoding Encoding
+	// LineEnding is the line ending of the output. By default a Config is
+	// written with LF line endings and a Document keeps the line endings of
+	// its lines, see Document.LineEnding.
+	LineEnding LineEnding
+
+	// MinimalQuoting only quotes keys and values if needed to read them back,
+	// e.g. if a value starts with whitespace or contains a comment character.
+	// By default all keys and values are quoted.
+	MinimalQuoting bool
+	// SpaceAroundSeparator writes "key = value", rather than "key=value".
+	SpaceAroundSeparator bool
+	// AlignSeparators aligns the separators of all keys in a section by
+	// padding the keys with spaces.
+	AlignSeparators bool
+
+	// SectionLess and KeyLess determine the order of the sections and the
+	// keys in a section, see `sort.Slice`. By default the sections and keys
+	// of a Config are sorted alphabetically and those of a struct are in the
+	// order of the fields. The global section always comes first.
+	SectionLess func(a, b string) bool
+	KeyLess     func(section, a, b string) bool
+
+	// Order is a document, e.g. the one the configuration was parsed from, of
+	// which the order of the sections and keys is used, see
+	// Document.Sections and Document.Keys. Sections and keys that aren't in
+	// the document follow in the default order. SectionLess and KeyLess still
+	// apply, keeping the order of the document for equal sections or keys.
+	//
+	//	doc, err := ini.ParseDocument(f)
+	//	// Handle error and change the configuration.
+	//	enc := ini.NewEncoder(w)
+	//	enc.Order = doc
+	//	err = enc.Encode(config)
+	Order *Document
+
+	// Header is a comment written at the start of the output, each line
+	// prefixed with "; ".
+	Header string
+
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to the writer.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the configuration, either a Config, a Document or a struct, to
+// the stream. A Config is written like Config.WriteTo, a Document like
+// Document.WriteTo and a struct is converted into a Config first, see Marshal.
+// All are written in the encoding and with the line endings of the encoder.
+//
+// Like Config.WriteTo it returns an error if the configuration can't be parsed
+// back into the same configuration, with any of the options of the encoder. It
+// also returns an error if a character can't be represented in the encoding.
+func (e *Encoder) Encode(v interface{}) error {
+	var buf *bytes.Buffer
+	switch v := v.(type) {
+	case Config:
+		if err := v.check(); err != nil {
+			return err
+		}
+		buf = e.configBuffer(v, e.order(v, nil))
+	case *Config:
+		if err := v.check(); err != nil {
+			return err
+		}
+		buf = e.configBuffer(*v, e.order(*v, nil))
+	case Document:
+		buf = v.buffer()
+	case *Document:
+		buf = v.buffer()
+	default:
+		c, order, err := marshal(v)
+		if err != nil {
+			return err
+		} else if err := c.check(); err != nil {
+			return err
+		}
+		buf = e.configBuffer(c, e.order(c, order))
+	}
+
+	output := buf.Bytes()
+	if e.LineEnding != AutoLineEnding {
+		output = convertLineEndings(output, e.LineEnding)
+	}
+	output, err := encode(output, e.Encoding)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(output)
+	return err
+}
+
+// Order is the order of the sections and of the keys in the sections of a
+// configuration.
+type order struct {
+	sections []string
+	keys     map[string][]string
+}
+
+// SortedOrder returns the alphabetical order of the configuration, with the
+// global section first.
+func sortedOrder(c Config) *order {
+	o := &order{sections: getConfigSectionsAlpha(c), keys: make(map[string][]string, len(c))}
+	for _, section := range o.sections {
+		o.keys[section] = getSectionKeysAlpha(c[section])
+	}
+	return o
+}
+
+// Order returns the order of the configuration using the document in
+// Encoder.Order, if any, followed by the order in o. If o is nil the
+// alphabetical order is used.
+func (e *Encoder) order(c Config, o *order) *order {
+	if e.Order == nil {
+		return o
+	} else if o == nil {
+		o = sortedOrder(c)
+	}
+
+	result := &order{keys: make(map[string][]string, len(o.keys))}
+	// The global section is always first, even if it's not in the
+	// configuration, as it has no header.
+	result.sections = inOrder(e.Order.Sections(), o.sections, func(section string) bool {
+		_, ok := c[section]
+		return ok || section == Global
+	})
+	for _, section := range result.sections {
+		result.keys[section] = inOrder(e.Order.Keys(section), o.keys[section], func(key string) bool {
+			_, ok := c[section][key]
+			return ok
+		})
+	}
+	return result
+}
+
+// InOrder returns the names in first that exist, followed by the names in
+// second that aren't in first.
+func inOrder(first, second []string, exists func(name string) bool) []string {
+	names := make([]string, 0, len(second))
+	seen := make(map[string]bool, len(first))
+	for _, name := range first {
+		if exists(name) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range second {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ConfigBuffer creates a `bytes.Buffer` with the ini formatted configuration,
+// formatted using the options of the encoder. If order is nil the sections
+// and keys are sorted alphabetically.
+func (e *Encoder) configBuffer(c Config, o *order) *bytes.Buffer {
+	if o == nil {
+		o = sortedOrder(c)
+	}
+
+	var buf bytes.Buffer
+	if e.Header != "" {
+		for _, line := range strings.Split(e.Header, "\n") {
+			buf.WriteString(strings.TrimRight("; "+line, " \r") + "\n")
+		}
+		buf.WriteString("\n")
+	}
+
+	for _, sectionName := range e.sortSections(o.sections) {
+		if sectionName != Global {
+			buf.WriteString(parseOptions.formatSection(sectionName) + "\n")
+		}
+
+		keys := e.sortKeys(sectionName, o.keys[sectionName])
+		formatted := make([]string, len(keys))
+		var width int
+		for i, key := range keys {
+			formatted[i] = e.formatKey(key)
+			if n := utf8.RuneCountInString(formatted[i]); n > width {
+				width = n
+			}
+		}
+
+		for i, key := range keys {
+			buf.WriteString(formatted[i])
+			if e.AlignSeparators {
+				padding := width - utf8.RuneCountInString(formatted[i])
+				buf.WriteString(strings.Repeat(" ", padding))
+			}
+			buf.WriteString(e.separator())
+			buf.WriteString(e.formatValue(c[sectionName][key]))
+			buf.WriteByte('\n')
+		}
+		buf.WriteByte('\n')
+	}
+	return &buf
+}
+
+// SortSections returns the sections sorted using SectionLess, if set.
+func (e *Encoder) sortSections(sections []string) []string {
+	if e.SectionLess == nil {
+		return sections
+	}
+	sections = append([]string(nil), sections...)
+	sort.SliceStable(sections, func(i, j int) bool {
+		if sections[i] == Global || sections[j] == Global {
+			return sections[j] != Global
+		}
+		return e.SectionLess(sections[i], sections[j])
+	})
+	return sections
+}
+
+// SortKeys returns the keys of the section sorted using KeyLess, if set.
+func (e *Encoder) sortKeys(section string, keys []string) []string {
+	if e.KeyLess == nil {
+		return keys
+	}
+	keys = append([]string(nil), keys...)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return e.KeyLess(section, keys[i], keys[j])
+	})
+	return keys
+}
+
+// The options used to format keys and values with minimal quoting, i.e. the
+// options used by Parse.
+var parseOptions, _ = Options{}.withDefaults()
+
+func (e *Encoder) formatKey(key string) string {
+	if e.MinimalQuoting {
+		return parseOptions.formatKey(key)
+	}
+	return strconv.Quote(key)
+}
+
+func (e *Encoder) formatValue(value string) string {
+	if e.MinimalQuoting {
+		return parseOptions.formatValue(value)
+	}
+	return strconv.Quote(value)
+}
+
+func (e *Encoder) separator() string {
+	if e.SpaceAroundSeparator {
+		return " = "
+	}
+	return "="
+}
@@ -0,0 +1,197 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncoderFormat(t *testing.T) {
+	t.Parallel()
+	c := Config{
+		Global:     {"name": "app", "#tag": "v1"},
+		"database": {"host": "localhost", "user": "bob ; admin", "password": " secret "},
+		"cache":    {"size": "10", "key=value": "a\nb"},
+	}
+	reverse := func(a, b string) bool { return a > b }
+
+	tests := []struct {
+		setup    func(enc *Encoder)
+		expected string
+	}{
+		{func(enc *Encoder) {}, c.String()},
+		{
+			func(enc *Encoder) { enc.MinimalQuoting = true },
+			"\"#tag\"=v1\nname=app\n\n" +
+				"[cache]\n\"key=value\"=\"a\\nb\"\nsize=10\n\n" +
+				"[database]\nhost=localhost\npassword=\" secret \"\nuser=\"bob ; admin\"\n\n",
+		},
+		{
+			func(enc *Encoder) {
+				enc.MinimalQuoting = true
+				enc.SpaceAroundSeparator = true
+				enc.AlignSeparators = true
+			},
+			"\"#tag\" = v1\nname   = app\n\n" +
+				"[cache]\n\"key=value\" = \"a\\nb\"\nsize        = 10\n\n" +
+				"[database]\nhost     = localhost\npassword = \" secret \"\nuser     = \"bob ; admin\"\n\n",
+		},
+		{
+			func(enc *Encoder) {
+				enc.MinimalQuoting = true
+				enc.SectionLess = reverse
+				enc.KeyLess = func(section, a, b string) bool { return reverse(a, b) }
+			},
+			"name=app\n\"#tag\"=v1\n\n" +
+				"[database]\nuser=\"bob ; admin\"\npassword=\" secret \"\nhost=localhost\n\n" +
+				"[cache]\nsize=10\n\"key=value\"=\"a\\nb\"\n\n",
+		},
+		{
+			func(enc *Encoder) {
+				enc.MinimalQuoting = true
+				enc.KeyLess = func(section, a, b string) bool { return false }
+				enc.Header = "Generated file.\n\nDo not edit."
+			},
+			"; Generated file.\n;\n; Do not edit.\n\n" +
+				"\"#tag\"=v1\nname=app\n\n" +
+				"[cache]\n\"key=value\"=\"a\\nb\"\nsize=10\n\n" +
+				"[database]\nhost=localhost\npassword=\" secret \"\nuser=\"bob ; admin\"\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		test.setup(enc)
+		if err := enc.Encode(c); err != nil {
+			t.Fatalf("Unexpected error encoding: %s", err.Error())
+		} else if got := buf.String(); got != test.expected {
+			t.Fatalf("Expected to write %q, but got %q", test.expected, got)
+		}
+
+		got, err := Parse(&buf)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %s", test.expected, err.Error())
+		} else if !reflect.DeepEqual(got, c) {
+			t.Fatalf("Expected %v, but got %v", c, got)
+		}
+	}
+}
+
+func TestEncoderDocument(t *testing.T) {
+	t.Parallel()
+	content := "; comment\nkey = value\n"
+	doc, err := ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	for _, v := range []interface{}{doc, *doc} {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(v); err != nil {
+			t.Fatalf("Unexpected error encoding %T: %s", v, err.Error())
+		} else if got := buf.String(); got != content {
+			t.Fatalf("Expected encoding %T to write %q, but got %q", v, content, got)
+		}
+	}
+}
+
+func TestEncoderStructOrder(t *testing.T) {
+	t.Parallel()
+	data := struct {
+		Zone     string
+		Name     string
+		Server   struct{ Port, Host string }
+		Database struct{ User string }
+	}{Zone: "eu", Name: "app"}
+	data.Server.Port = "80"
+	data.Server.Host = "localhost"
+	data.Database.User = "bob"
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.MinimalQuoting = true
+	if err := enc.Encode(&data); err != nil {
+		t.Fatalf("Unexpected error encoding: %s", err.Error())
+	}
+	expected := "Zone=eu\nName=app\n\n[Server]\nPort=80\nHost=localhost\n\n[Database]\nUser=bob\n\n"
+	if got := buf.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+}
+
+func TestEncoderOrder(t *testing.T) {
+	t.Parallel()
+	content := "zone = eu\nname = app\n[plugins]\nzip = 1\nauth = 2\n[cache]\nsize = 3\n"
+	doc, err := ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	c := doc.Config()
+	c["plugins"]["log"] = "4"
+	c["plugins"]["debug"] = "5"
+	c["extra"] = Section{"key": "value"}
+	delete(c, "cache")
+
+	tests := []struct {
+		setup    func(enc *Encoder)
+		expected string
+	}{
+		{func(enc *Encoder) {}, "zone=eu\nname=app\n\n" +
+			"[plugins]\nzip=1\nauth=2\ndebug=5\nlog=4\n\n[extra]\nkey=value\n\n"},
+		{func(enc *Encoder) { enc.SectionLess = func(a, b string) bool { return false } },
+			"zone=eu\nname=app\n\n" +
+				"[plugins]\nzip=1\nauth=2\ndebug=5\nlog=4\n\n[extra]\nkey=value\n\n"},
+		{func(enc *Encoder) { enc.SectionLess = func(a, b string) bool { return a < b } },
+			"zone=eu\nname=app\n\n" +
+				"[extra]\nkey=value\n\n[plugins]\nzip=1\nauth=2\ndebug=5\nlog=4\n\n"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.MinimalQuoting = true
+		enc.Order = doc
+		test.setup(enc)
+		if err := enc.Encode(c); err != nil {
+			t.Fatalf("Unexpected error encoding: %s", err.Error())
+		} else if got := buf.String(); got != test.expected {
+			t.Fatalf("Expected %q, but got %q", test.expected, got)
+		}
+	}
+
+	// The global section has no header, so it's always first.
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.MinimalQuoting = true
+	enc.Order = doc
+	if err := enc.Encode(Config{"plugins": {"auth": "1"}}); err != nil {
+		t.Fatalf("Unexpected error encoding: %s", err.Error())
+	} else if expected, got := "\n[plugins]\nauth=1\n\n", buf.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+
+	// The order of the document takes precedence over the order of the fields.
+	data := struct {
+		Name    string `ini:"name"`
+		Zone    string `ini:"zone"`
+		Plugins struct {
+			Auth string `ini:"auth"`
+			Zip  string `ini:"zip"`
+		} `ini:"plugins"`
+	}{Name: "app", Zone: "eu"}
+	buf.Reset()
+	if err := enc.Encode(data); err != nil {
+		t.Fatalf("Unexpected error encoding: %s", err.Error())
+	}
+	expected := "zone=eu\nname=app\n\n[plugins]\nzip=\nauth=\n\n"
+	if got := buf.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a character encoding of ini formatted input or output.
+type Encoding uint8
+
+// The supported encodings.
+const (
+	// UTF8 is UTF-8 without a byte order mark, the default.
+	UTF8 Encoding = iota
+	// UTF8BOM is UTF-8 starting with a byte order mark.
+	UTF8BOM
+	// UTF16LE and UTF16BE are little and big endian UTF-16, both starting with
+	// a byte order mark.
+	UTF16LE
+	UTF16BE
+	// Latin1 is ISO-8859-1.
+	Latin1
+	// Windows1252 is the Windows code page 1252, a superset of Latin-1.
+	Windows1252
+)
+
+// Byte order marks.
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Characters of Windows-1252 in the range 0x80 to 0x9F, the other bytes have
+// the same value as in Latin-1. The five unused bytes map to the Latin-1
+// control characters so that any input can be written back.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// String returns the name of the encoding.
+func (enc Encoding) String() string {
+	switch enc {
+	case UTF8:
+		return "UTF-8"
+	case UTF8BOM:
+		return "UTF-8 with BOM"
+	case UTF16LE:
+		return "UTF-16LE"
+	case UTF16BE:
+		return "UTF-16BE"
+	case Latin1:
+		return "Latin-1"
+	case Windows1252:
+		return "Windows-1252"
+	default:
+		return "Encoding(" + strconv.Itoa(int(enc)) + ")"
+	}
+}
+
+func (enc Encoding) isValid() bool {
+	return enc <= Windows1252
+}
+
+// Bom returns the byte order mark of the encoding, if any.
+func (enc Encoding) bom() []byte {
+	switch enc {
+	case UTF8BOM:
+		return bomUTF8
+	case UTF16LE:
+		return bomUTF16LE
+	case UTF16BE:
+		return bomUTF16BE
+	default:
+		return nil
+	}
+}
+
+// DecodeReader returns a reader that decodes the input into UTF-8 and the
+// encoding of the input. A byte order mark determines the encoding, if
+// present, otherwise the input is assumed to be in the encoding enc.
+func decodeReader(r io.Reader, enc Encoding) (io.Reader, Encoding) {
+	br := bufio.NewReader(r)
+	// Errors are returned on the next read.
+	start, _ := br.Peek(len(bomUTF8))
+	for _, detect := range []Encoding{UTF8BOM, UTF16LE, UTF16BE} {
+		if bytes.HasPrefix(start, detect.bom()) {
+			br.Discard(len(detect.bom()))
+			enc = detect
+			break
+		}
+	}
+
+	switch enc {
+	case UTF8, UTF8BOM:
+		return br, enc
+	default:
+		return &transcoder{r: br, enc: enc}, enc
+	}
+}
+
+// Transcoder is a reader that decodes UTF-16, Latin-1 or Windows-1252 input
+// into UTF-8.
+type transcoder struct {
+	r   *bufio.Reader
+	enc Encoding
+	// Decoded bytes not yet read.
+	buf []byte
+	err error
+}
+
+// Number of characters decoded at once.
+const transcodeSize = 512
+
+func (t *transcoder) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+		t.fill()
+	}
+
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// Fill decodes the next characters into the buffer.
+func (t *transcoder) fill() {
+	for i := 0; i < transcodeSize && t.err == nil; i++ {
+		var r rune
+		switch t.enc {
+		case UTF16LE, UTF16BE:
+			r, t.err = t.readUTF16()
+		default:
+			var b byte
+			b, t.err = t.r.ReadByte()
+			r = rune(b)
+			if t.enc == Windows1252 && b >= 0x80 && b <= 0x9F {
+				r = windows1252[b-0x80]
+			}
+		}
+
+		if t.err == nil {
+			t.buf = utf8.AppendRune(t.buf, r)
+		}
+	}
+}
+
+// ReadUTF16 reads a single UTF-16 encoded character. Invalid characters,
+// including an incomplete character at the end of the input, are decoded as
+// utf8.RuneError.
+func (t *transcoder) readUTF16() (rune, error) {
+	r1, err := t.readUTF16Unit()
+	if err == io.ErrUnexpectedEOF {
+		return utf8.RuneError, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if !utf16.IsSurrogate(r1) {
+		return r1, nil
+	}
+
+	// Only peek at the second half, so that an invalid character doesn't
+	// consume the next character.
+	next, err := t.r.Peek(2)
+	if err != nil {
+		return utf8.RuneError, nil
+	}
+	r2 := t.unit(next)
+	r := utf16.DecodeRune(r1, r2)
+	if r != utf8.RuneError {
+		t.r.Discard(2)
+	}
+	return r, nil
+}
+
+func (t *transcoder) readUTF16Unit() (rune, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(t.r, b[:]); err != nil {
+		return 0, err
+	}
+	return t.unit(b[:]), nil
+}
+
+// Unit returns the UTF-16 code unit in the first two bytes of b.
+func (t *transcoder) unit(b []byte) rune {
+	if t.enc == UTF16LE {
+		return rune(b[0]) | rune(b[1])<<8
+	}
+	return rune(b[0])<<8 | rune(b[1])
+}
+
+// Encode encodes the UTF-8 input into the encoding, including the byte order
+// mark for encodings that use one. It returns an error if a character can't
+// be represented in the encoding.
+func encode(input []byte, enc Encoding) ([]byte, error) {
+	output := append([]byte(nil), enc.bom()...)
+	switch enc {
+	case UTF8, UTF8BOM:
+		return append(output, input...), nil
+	}
+
+	for len(input) != 0 {
+		r, size := utf8.DecodeRune(input)
+		input = input[size:]
+
+		switch enc {
+		case UTF16LE, UTF16BE:
+			units := []rune{r}
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				units = []rune{r1, r2}
+			}
+			for _, u := range units {
+				if enc == UTF16LE {
+					output = append(output, byte(u), byte(u>>8))
+				} else {
+					output = append(output, byte(u>>8), byte(u))
+				}
+			}
+		default:
+			b, ok := encodeByte(r, enc)
+			if !ok {
+				return nil, fmt.Errorf("ini: can't encode %q in %s", r, enc)
+			}
+			output = append(output, b)
+		}
+	}
+	return output, nil
+}
+
+// EncodeByte encodes the character in the single byte encoding enc, if
+// possible.
+func encodeByte(r rune, enc Encoding) (byte, bool) {
+	if enc == Windows1252 {
+		for i, c := range windows1252 {
+			if c == r {
+				return byte(0x80 + i), true
+			}
+		}
+		if r >= 0x80 && r <= 0x9F {
+			return 0, false
+		}
+	}
+	if r > 0xFF {
+		return 0, false
+	}
+	return byte(r), true
+}
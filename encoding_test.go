@@ -0,0 +1,180 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"unicode/utf16"
+)
+
+const encodingContent = "name = Zoë\n[€uro]\nquote = “a” – b\n"
+
+// EncodeUTF16 encodes s in UTF-16, with a byte order mark.
+func encodeUTF16(s string, littleEndian bool) []byte {
+	var output []byte
+	for _, u := range utf16.Encode([]rune("\uFEFF" + s)) {
+		if littleEndian {
+			output = append(output, byte(u), byte(u>>8))
+		} else {
+			output = append(output, byte(u>>8), byte(u))
+		}
+	}
+	return output
+}
+
+func TestParseEncoding(t *testing.T) {
+	t.Parallel()
+	expected := Config{
+		Global: {"name": "Zoë"},
+		"€uro": {"quote": "“a” – b"},
+	}
+
+	tests := []struct {
+		input    []byte
+		opts     Options
+		encoding Encoding
+	}{
+		{[]byte(encodingContent), Options{}, UTF8},
+		{append([]byte("\xEF\xBB\xBF"), encodingContent...), Options{}, UTF8BOM},
+		{encodeUTF16(encodingContent, true), Options{}, UTF16LE},
+		{encodeUTF16(encodingContent, false), Options{}, UTF16BE},
+		{[]byte("name = Zo\xEB\n[\x80uro]\nquote = \x93a\x94 \x96 b\n"),
+			Options{Encoding: Windows1252}, Windows1252},
+		// A byte order mark takes precedence.
+		{encodeUTF16(encodingContent, true), Options{Encoding: Latin1}, UTF16LE},
+	}
+
+	for _, test := range tests {
+		got, err := ParseWithOptions(bytes.NewReader(test.input), test.opts)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %s", test.input, err.Error())
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("Expected parsing %q to return %v, but got %v", test.input, expected, got)
+		}
+
+		doc, err := ParseDocumentWithOptions(bytes.NewReader(test.input), test.opts)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing document %q: %s", test.input, err.Error())
+		} else if enc := doc.Encoding(); enc != test.encoding {
+			t.Fatalf("Expected the encoding of %q to be %s, but got %s", test.input,
+				test.encoding, enc)
+		} else if got := doc.String(); got != encodingContent {
+			t.Fatalf("Expected Document.String() to return %q, but got %q",
+				encodingContent, got)
+		}
+
+		var buf bytes.Buffer
+		if _, err := doc.WriteTo(&buf); err != nil {
+			t.Fatalf("Unexpected error writing document: %s", err.Error())
+		} else if !bytes.Equal(buf.Bytes(), test.input) {
+			t.Fatalf("Expected Document.WriteTo to write %q, but got %q",
+				test.input, buf.Bytes())
+		}
+	}
+}
+
+func TestParseLatin1(t *testing.T) {
+	t.Parallel()
+	input := "key = caf\xE9 \x80\n"
+	c, err := ParseWithOptions(strings.NewReader(input), Options{Encoding: Latin1})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	} else if got := c[Global]["key"]; got != "café \u0080" {
+		t.Fatalf("Expected the value to be %q, but got %q", "café \u0080", got)
+	}
+}
+
+func TestParseInvalidUTF16(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    []byte
+		expected string
+	}{
+		// Unpaired surrogate.
+		{[]byte{0xFF, 0xFE, 'a', 0, '=', 0, 0x00, 0xD8, 'b', 0}, "�b"},
+		// Odd number of bytes.
+		{[]byte{0xFF, 0xFE, 'a', 0, '=', 0, 'b', 0, 'c'}, "b�"},
+		// Surrogate pair.
+		{[]byte{0xFE, 0xFF, 0, 'a', 0, '=', 0xD8, 0x3D, 0xDE, 0x00}, "😀"},
+	}
+
+	for _, test := range tests {
+		c, err := Parse(bytes.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %s", test.input, err.Error())
+		} else if got := c[Global]["a"]; got != test.expected {
+			t.Fatalf("Expected the value to be %q, but got %q", test.expected, got)
+		}
+	}
+}
+
+func TestParseFSEncoding(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"app.ini":   {Data: []byte("\xEF\xBB\xBFinclude = utf16.ini\n")},
+		"utf16.ini": {Data: encodeUTF16("key = value\n", true)},
+	}
+
+	c, err := ParseFS(fsys, "app.ini", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	} else if got := c[Global]["key"]; got != "value" {
+		t.Fatalf("Expected the value to be %q, but got %q", "value", got)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	t.Parallel()
+	c := Config{Global: {"name": "Zoë"}, "€uro": {"price": "€5"}}
+	utf8Output := c.String()
+
+	tests := []struct {
+		encoding Encoding
+		expected []byte
+	}{
+		{UTF8, []byte(utf8Output)},
+		{UTF8BOM, append([]byte("\xEF\xBB\xBF"), utf8Output...)},
+		{UTF16LE, encodeUTF16(utf8Output, true)},
+		{UTF16BE, encodeUTF16(utf8Output, false)},
+		{Windows1252, []byte(strings.NewReplacer("ë", "\xEB", "€", "\x80").Replace(utf8Output))},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.Encoding = test.encoding
+		if err := enc.Encode(c); err != nil {
+			t.Fatalf("Unexpected error encoding in %s: %s", test.encoding, err.Error())
+		} else if !bytes.Equal(buf.Bytes(), test.expected) {
+			t.Fatalf("Expected encoding in %s to write %q, but got %q",
+				test.encoding, test.expected, buf.Bytes())
+		}
+
+		got, err := ParseWithOptions(&buf, Options{Encoding: test.encoding})
+		if err != nil {
+			t.Fatalf("Unexpected error parsing: %s", err.Error())
+		} else if !reflect.DeepEqual(got, c) {
+			t.Fatalf("Expected %v, but got %v", c, got)
+		}
+	}
+
+	enc := NewEncoder(&bytes.Buffer{})
+	enc.Encoding = Latin1
+	if err := enc.Encode(&c); err == nil || err.Error() != `ini: can't encode '€' in Latin-1` {
+		t.Fatalf("Expected an error encoding in Latin-1, but got %v", err)
+	}
+	if err := enc.Encode("string"); err == nil ||
+		err.Error() != "ini: can't encode type string, expected a struct or a pointer to a struct" {
+		t.Fatalf("Expected an error encoding a string, but got %v", err)
+	}
+	if _, err := ParseWithOptions(strings.NewReader(""), Options{Encoding: 100}); err == nil ||
+		err.Error() != "ini: invalid encoding Encoding(100)" {
+		t.Fatalf("Expected an error for an invalid encoding, but got %v", err)
+	}
+}
@@ -4,72 +4,284 @@
 
 package ini
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
-type syntaxError struct {
+// SyntaxError is an error in the syntax of the input, returned by the parse
+// functions.
+//
+//	var synErr ini.SyntaxError
+//	if errors.As(err, &synErr) {
+//		fmt.Printf("error on line %d: %s\n", synErr.LineNumber, synErr.Message)
+//	}
+type SyntaxError struct {
+	// File is the name of the file in which the error is found, only set when
+	// parsing with ParseFS or ParseDocumentFS.
+	File       string
 	LineNumber int
-	Message    string
+	// Column is the position of the error in the line, in runes starting at
+	// one, or zero if unknown. Source is the line in which the error is
+	// found, without the line ending, only set if the column is known.
+	Column  int
+	Source  string
+	Message string
 }
 
-func (err syntaxError) Error() string {
+func (err SyntaxError) Error() string {
+	if err.File != "" {
+		return fmt.Sprintf("ini: syntax error in %s on line %d: %s",
+			err.File, err.LineNumber, err.Message)
+	}
 	return fmt.Sprintf("ini: syntax error on line %d: %s",
 		err.LineNumber, err.Message)
 }
 
-type overflowError struct {
+// OffsetError is an error at a specific byte offset in a line, used by the
+// parser to find the line and position of a syntax error.
+type offsetError struct {
+	offset int
+	msg    string
+}
+
+func (err offsetError) Error() string {
+	return err.msg
+}
+
+// ErrorList is a list of syntax errors, returned by the parse functions in
+// recovery mode, see Options.Recover. The errors are in the order in which
+// they are found.
+type ErrorList []error
+
+// Error returns the first error and the number of other errors.
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "ini: no errors"
+	case 1:
+		return list[0].Error()
+	case 2:
+		return list[0].Error() + " (and 1 more error)"
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", list[0].Error(), len(list)-1)
+	}
+}
+
+// Unwrap returns the errors in the list, for use with `errors.Is` and
+// `errors.As`.
+func (list ErrorList) Unwrap() []error {
+	return list
+}
+
+// LimitError is returned if the input exceeds one of the limits in
+// Options.Limits.
+type LimitError struct {
+	// Limit is the name of the exceeded field in Limits, e.g. "MaxSections",
+	// and Max its value.
+	Limit string
+	Max   int64
+	// Position is the line at which the limit is exceeded, the column isn't
+	// set.
+	Position Position
+}
+
+func (err LimitError) Error() string {
+	var what string
+	switch err.Limit {
+	case limitLineLength:
+		what = fmt.Sprintf("maximum line length of %d bytes", err.Max)
+	case limitValueSize:
+		what = fmt.Sprintf("maximum value size of %d bytes", err.Max)
+	case limitSections:
+		what = fmt.Sprintf("maximum of %d sections", err.Max)
+	case limitKeysPerSection:
+		what = fmt.Sprintf("maximum of %d keys per section", err.Max)
+	case limitBytes:
+		what = fmt.Sprintf("maximum input size of %d bytes", err.Max)
+	default:
+		what = fmt.Sprintf("limit %s of %d", err.Limit, err.Max)
+	}
+	if err.Position.File != "" {
+		return fmt.Sprintf("ini: exceeded the %s in %s on line %d", what,
+			err.Position.File, err.Position.Line)
+	}
+	return fmt.Sprintf("ini: exceeded the %s on line %d", what, err.Position.Line)
+}
+
+// IncludedError is an error from parsing an included file, it's returned as
+// is, rather than as a syntax error in the including file.
+type includedError struct {
+	error
+}
+
+// OverflowError is returned if a value is a valid number, but doesn't fit in
+// the type it's decoded into. It wraps `strconv.ErrRange`.
+type OverflowError struct {
 	Value string
 	Type  string
 }
 
-func (err overflowError) Error() string {
+func (err OverflowError) Error() string {
 	return fmt.Sprintf("ini: can't convert '%s' to type %s, it overflows the type",
 		err.Value, err.Type)
 }
 
-type covertionError struct {
+// Unwrap returns `strconv.ErrRange`.
+func (err OverflowError) Unwrap() error {
+	return strconv.ErrRange
+}
+
+// ConversionError is returned if a value can't be converted into the type it's
+// decoded into. It wraps the error returned by the conversion, e.g. a
+// `*strconv.NumError` or `*time.ParseError`, if any.
+type ConversionError struct {
 	Value string
 	Type  string
+	Err   error
 }
 
-func (err covertionError) Error() string {
+func (err ConversionError) Error() string {
 	return fmt.Sprintf("ini: can't convert '%s' to type %s", err.Value, err.Type)
 }
 
+// Unwrap returns the error returned by the conversion.
+func (err ConversionError) Unwrap() error {
+	return err.Err
+}
+
+// DecodeError is returned if a value can't be decoded into a field of a
+// struct. It wraps the OverflowError or ConversionError that describes the
+// problem.
+//
+//	var decErr ini.DecodeError
+//	if errors.As(err, &decErr) {
+//		fmt.Printf("invalid value for %s: %q\n", decErr.Field, decErr.Value)
+//	}
+type DecodeError struct {
+	// Section and Key of the value.
+	Section string
+	Key     string
+	// Field is the path to the struct field, e.g. "Database.Port".
+	Field string
+	// Value is the value as found in the configuration and Type the type of
+	// the field.
+	Value string
+	Type  reflect.Type
+	// Position of the key in the source. It's only known when decoding using
+	// Decode, Document.Decode or Metadata.Decode, Config.Decode leaves it as
+	// the zero value.
+	Position Position
+	Err      error
+}
+
+func (err DecodeError) Error() string {
+	section := err.Section
+	if section == Global {
+		section = globalName
+	}
+	if err.Position.IsValid() {
+		return fmt.Sprintf("ini: error decoding %q in section %q (%s): %s",
+			err.Key, section, err.Position, err.Err.Error())
+	}
+	return fmt.Sprintf("ini: error decoding %q in section %q: %s",
+		err.Key, section, err.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (err DecodeError) Unwrap() error {
+	return err.Err
+}
+
+// Position returns the position of the error.
+func (err SyntaxError) Position() Position {
+	return Position{File: err.File, Line: err.LineNumber, Column: err.Column}
+}
+
+// FormatError formats the error for displaying it to a user, much like a
+// compiler diagnostic. A syntax error is formatted as its location followed by
+// the line with a caret under the problem:
+//
+//	app.ini:3:7: unexpected "x", expected the separator "="
+//		"key" x = value
+//		      ^
+//
+// An ErrorList is formatted as all its errors on separate lines. Other errors
+// are returned as is.
+func FormatError(err error) string {
+	switch err := err.(type) {
+	case ErrorList:
+		formatted := make([]string, len(err))
+		for i, err := range err {
+			formatted[i] = FormatError(err)
+		}
+		return strings.Join(formatted, "\n")
+	case SyntaxError:
+		msg := err.Position().String() + ": " + err.Message
+		if err.Column == 0 {
+			return msg
+		}
+
+		// Keep tabs so the caret lines up with the source line.
+		var caret strings.Builder
+		column := 1
+		for _, r := range err.Source {
+			if column == err.Column {
+				break
+			}
+			column++
+			if r == '\t' {
+				caret.WriteByte('\t')
+			} else {
+				caret.WriteByte(' ')
+			}
+		}
+		caret.WriteByte('^')
+		return msg + "\n\t" + err.Source + "\n\t" + caret.String()
+	default:
+		return err.Error()
+	}
+}
+
 func createSyntaxError(lineNumber int, msg string) error {
-	return syntaxError{
+	return SyntaxError{
 		LineNumber: lineNumber,
 		Message:    msg,
 	}
 }
 
 func createOverflowError(value, t string) error {
-	return overflowError{
+	return OverflowError{
 		Value: value,
 		Type:  t,
 	}
 }
 
-func createCovertionError(value, t string) error {
-	return covertionError{
+func createCovertionError(value, t string, err error) error {
+	return ConversionError{
 		Value: value,
 		Type:  t,
+		Err:   err,
 	}
 }
 
-// IsSyntaxError checks if an error is a syntax error.
+// IsSyntaxError checks if an error is, or wraps, a SyntaxError.
 func IsSyntaxError(err error) bool {
-	_, ok := err.(syntaxError)
-	return ok
+	var synErr SyntaxError
+	return errors.As(err, &synErr)
 }
 
-// IsOverflowError checks if an error is an overflow error.
+// IsOverflowError checks if an error is, or wraps, an OverflowError.
 func IsOverflowError(err error) bool {
-	_, ok := err.(overflowError)
-	return ok
+	var overflowErr OverflowError
+	return errors.As(err, &overflowErr)
 }
 
-// IsCovertionError checks if an error is a covertion error.
+// IsCovertionError checks if an error is, or wraps, a ConversionError.
 func IsCovertionError(err error) bool {
-	_, ok := err.(covertionError)
-	return ok
+	var convErr ConversionError
+	return errors.As(err, &convErr)
 }
@@ -6,7 +6,11 @@ package ini
 
 import (
 	"errors"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSyntaxError(t *testing.T) {
@@ -54,7 +58,7 @@ func TestCovertionError(t *testing.T) {
 		t.Fatalf("Expected IsCovertionError(%v) to return false", regularError)
 	}
 
-	covertionError := createCovertionError("string", "int8")
+	covertionError := createCovertionError("string", "int8", nil)
 	if got := IsCovertionError(covertionError); !got {
 		t.Fatalf("Expected IsCovertionError(%v) to return true", covertionError)
 	}
@@ -65,3 +69,148 @@ func TestCovertionError(t *testing.T) {
 			expected, covertionError.Error())
 	}
 }
+
+func TestErrorList(t *testing.T) {
+	t.Parallel()
+	err1 := createSyntaxError(1, "first")
+	err2 := createSyntaxError(2, "second")
+	err3 := createSyntaxError(3, "third")
+	tests := []struct {
+		list     ErrorList
+		expected string
+	}{
+		{ErrorList{}, "ini: no errors"},
+		{ErrorList{err1}, "ini: syntax error on line 1: first"},
+		{ErrorList{err1, err2}, "ini: syntax error on line 1: first (and 1 more error)"},
+		{ErrorList{err1, err2, err3}, "ini: syntax error on line 1: first (and 2 more errors)"},
+	}
+
+	for _, test := range tests {
+		if got := test.list.Error(); got != test.expected {
+			t.Fatalf("Expected the error to be %q, but got %q", test.expected, got)
+		}
+	}
+
+	if !errors.Is(ErrorList{err1, err2}, err2) {
+		t.Fatal("Expected errors.Is to find the error in the list")
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{errors.New("some error"), "some error"},
+		{createSyntaxError(2, "error message"), "2: error message"},
+		{SyntaxError{File: "app.ini", LineNumber: 3, Column: 5, Source: "key x = value",
+			Message: "error message"},
+			"app.ini:3:5: error message\n\tkey x = value\n\t    ^"},
+		{SyntaxError{LineNumber: 1, Column: 3, Source: "\tи x", Message: "error message"},
+			"1:3: error message\n\t\tи x\n\t\t ^"},
+		{ErrorList{createSyntaxError(1, "first"), createSyntaxError(2, "second")},
+			"1: first\n2: second"},
+	}
+
+	for _, test := range tests {
+		if got := FormatError(test.err); got != test.expected {
+			t.Fatalf("Expected FormatError(%v) to return %q, but got %q",
+				test.err, test.expected, got)
+		}
+	}
+
+	_, err := ParseFS(includeFS, "error.ini", Options{})
+	expected := "invalid.ini:4:13: no separator found\n\tno separator\n\t            ^"
+	if got := FormatError(err); got != expected {
+		t.Fatalf("Expected FormatError to return %q, but got %q", expected, got)
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	t.Parallel()
+	_, err := Parse(strings.NewReader("[section]\nkey"))
+	var synErr SyntaxError
+	if !errors.As(err, &synErr) {
+		t.Fatalf("Expected a SyntaxError, but got %v", err)
+	} else if synErr.LineNumber != 2 || synErr.Message != "no separator found" {
+		t.Fatalf("Unexpected syntax error: %+v", synErr)
+	} else if got := synErr.Position().String(); got != "2:4" {
+		t.Fatalf("Expected the position to be %q, but got %q", "2:4", got)
+	}
+
+	_, err = ParseWithOptions(strings.NewReader("a\nb"), Options{Recover: true})
+	if !errors.As(err, &synErr) || synErr.LineNumber != 1 {
+		t.Fatalf("Expected to find the first SyntaxError in the ErrorList, but got %v", err)
+	}
+
+	var n uint8
+	err = DecodeValue("300", &n)
+	var overflowErr OverflowError
+	if !errors.As(err, &overflowErr) || overflowErr.Value != "300" || overflowErr.Type != "uint8" {
+		t.Fatalf("Expected an OverflowError, but got %v", err)
+	} else if !errors.Is(err, strconv.ErrRange) {
+		t.Fatal("Expected the OverflowError to wrap strconv.ErrRange")
+	}
+
+	var d time.Duration
+	err = DecodeValue("1 hour", &d)
+	var convErr ConversionError
+	if !errors.As(err, &convErr) || convErr.Value != "1 hour" || convErr.Type != "time.Duration" {
+		t.Fatalf("Expected a ConversionError, but got %v", err)
+	} else if convErr.Err == nil {
+		t.Fatal("Expected the ConversionError to wrap the underlying error")
+	}
+}
+
+type decodeErrorTestData struct {
+	Database struct {
+		Ports []int `ini:"port"`
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	t.Parallel()
+	content := "[database]\n  port = 80, abc\n"
+	expectedMsg := `ini: error decoding "port" in section "database": ` +
+		`ini: can't convert 'abc' to type int`
+
+	c, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	var dst decodeErrorTestData
+	err = c.Decode(&dst)
+	var decErr DecodeError
+	if !errors.As(err, &decErr) {
+		t.Fatalf("Expected a DecodeError, but got %v", err)
+	} else if err.Error() != expectedMsg {
+		t.Fatalf("Expected the error to be %q, but got %q", expectedMsg, err.Error())
+	}
+
+	if decErr.Section != "database" || decErr.Key != "port" ||
+		decErr.Field != "Database.Ports" || decErr.Value != "80, abc" ||
+		decErr.Type != reflect.TypeOf([]int(nil)) || decErr.Position.IsValid() {
+		t.Fatalf("Unexpected decode error: %+v", decErr)
+	}
+	if !IsCovertionError(err) {
+		t.Fatal("Expected the DecodeError to wrap a ConversionError")
+	} else if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatal("Expected the DecodeError to wrap strconv.ErrSyntax")
+	}
+
+	err = Decode(strings.NewReader(content), &dst)
+	if !errors.As(err, &decErr) {
+		t.Fatalf("Expected a DecodeError, but got %v", err)
+	}
+	expectedPos := Position{Line: 2, Column: 3}
+	if decErr.Position != expectedPos {
+		t.Fatalf("Expected the position to be %v, but got %v", expectedPos, decErr.Position)
+	}
+	expectedMsg = `ini: error decoding "port" in section "database" (2:3): ` +
+		`ini: can't convert 'abc' to type int`
+	if err.Error() != expectedMsg {
+		t.Fatalf("Expected the error to be %q, but got %q", expectedMsg, err.Error())
+	}
+}
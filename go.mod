@@ -0,0 +1,3 @@
+module github.com/Thomasdezeeuw/ini
+
+go 1.20
@@ -0,0 +1,216 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// The include directives, see ParseFS.
+const (
+	includeKey       = "include"
+	includeDirective = "!include"
+	includeDir       = "!includedir"
+)
+
+// The extensions of the files included by the "!includedir" directive.
+var includeDirExtensions = []string{".ini", ".cnf"}
+
+// ParseFS parses the ini formatted file with the name from the file system,
+// including the files named in include directives. The following directives
+// are supported:
+//
+//	include = base.ini
+//	!include host.ini
+//	!includedir conf.d
+//
+// The "!includedir" directive includes all files ending in ".ini" or ".cnf" in
+// the directory, in alphabetical order. Paths are relative to the directory
+// of the including file, paths starting with a slash are relative to the root
+// of the file system.
+//
+// The included file is parsed as if its lines were written in place of the
+// directive. It starts in the section of the directive, and after it's parsed
+// the including file continues in that same section. This means that the
+// duplicate section and key policies in the options also apply to sections and
+// keys in included files, for example to allow a host-specific file to
+// override the values of a base file:
+//
+//	opts := ini.Options{
+//		DuplicateSections: ini.SectionMerge,
+//		DuplicateKeys:     ini.KeyLastWins,
+//	}
+//	config, err := ini.ParseFS(os.DirFS("/etc/app"), "app.ini", opts)
+//
+// Syntax errors mention the file in which they are found. Including a file
+// that is already being parsed results in an include cycle error, and includes
+// can't be nested deeper than Options.MaxIncludeDepth.
+func ParseFS(fsys fs.FS, name string, opts Options) (Config, error) {
+	p, err := parseFS(fsys, name, opts, false)
+	if errs, ok := err.(ErrorList); ok {
+		return p.Config, errs
+	} else if err != nil {
+		return nil, err
+	}
+
+	return p.Config, nil
+}
+
+// ParseDocumentFS parses the ini formatted file with the name from the file
+// system into a Document, including the files named in include directives, see
+// ParseFS.
+//
+// The nodes of the included files are part of the document, with their File
+// set to the name of the included file. However only the lines of the file
+// itself are written, so changing or deleting lines from included files
+// returns an error. Keys added to the document are added to the file itself.
+func ParseDocumentFS(fsys fs.FS, name string, opts Options) (*Document, error) {
+	p, err := parseFS(fsys, name, opts, true)
+	if errs, ok := err.(ErrorList); ok {
+		return p.doc, errs
+	} else if err != nil {
+		return nil, err
+	}
+
+	return p.doc, nil
+}
+
+func parseFS(fsys fs.FS, name string, opts Options, withDoc bool) (*parser, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("ini: error opening: %s", err.Error())
+	}
+	defer f.Close()
+
+	p, err := newParser(f, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.fsys = fsys
+	p.files = []string{name}
+	if withDoc {
+		p.doc = &Document{opts: p.opts, encoding: p.encoding}
+	}
+	return p, p.parse()
+}
+
+// ParseLine parses a single line, see Options.parseLine. If includes are
+// supported it also parses include directives into include nodes.
+func (p *parser) parseLine(line []byte) (*Node, error) {
+	if p.fsys == nil {
+		return p.opts.parseLine(line)
+	}
+
+	trimmed := bytes.TrimSpace(line)
+	for _, directive := range []string{includeDir, includeDirective} {
+		rest := bytes.TrimPrefix(trimmed, []byte(directive))
+		if len(rest) == len(trimmed) || (len(rest) != 0 && !unicode.IsSpace(rune(rest[0]))) {
+			continue
+		}
+		return &Node{
+			Kind:  IncludeNode,
+			Key:   directive,
+			Value: string(bytes.TrimSpace(rest)),
+		}, nil
+	}
+
+	n, err := p.opts.parseLine(line)
+	if err == nil && n.Kind == KeyValueNode && n.Key == includeKey {
+		n.Kind = IncludeNode
+	}
+	return n, err
+}
+
+// Include includes the file, or the files in the directory for the
+// "!includedir" directive, with the name relative to the current file.
+func (p *parser) include(directive, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s path can't be empty", directive)
+	}
+
+	if strings.HasPrefix(name, "/") {
+		name = path.Clean(name[1:])
+	} else {
+		name = path.Join(path.Dir(p.file()), name)
+	}
+
+	if directive != includeDir {
+		return p.includeFile(name)
+	}
+
+	entries, err := fs.ReadDir(p.fsys, name)
+	if err != nil {
+		return fmt.Errorf("can't include directory %q: %s", name, pathErrorCause(err))
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && hasIncludeDirExtension(entry.Name()) {
+			if err := p.includeFile(path.Join(name, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IncludeFile parses the included file with the name, continuing in the
+// current section.
+func (p *parser) includeFile(name string) error {
+	for i, file := range p.files {
+		if file == name {
+			chain := append(append([]string(nil), p.files[i:]...), name)
+			return fmt.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	if len(p.files) > p.opts.MaxIncludeDepth {
+		return fmt.Errorf("can't include %q, includes are nested more than %d levels deep",
+			name, p.opts.MaxIncludeDepth)
+	}
+
+	f, err := p.fsys.Open(name)
+	if err != nil {
+		return fmt.Errorf("can't include %q: %s", name, pathErrorCause(err))
+	}
+	defer f.Close()
+
+	lines, section := p.lines, p.currentSection
+	// Every file can have its own byte order mark.
+	r, _ := decodeReader(f, p.opts.Encoding)
+	p.lines = newLineReader(r, p.opts, &p.bytesRead)
+	p.files = append(p.files, name)
+	p.lastKeyValue = nil
+
+	err = p.parseLines()
+
+	p.lines, p.currentSection = lines, section
+	p.files = p.files[:len(p.files)-1]
+	p.lastKeyValue = nil
+	if err != nil {
+		return includedError{err}
+	}
+	return nil
+}
+
+func hasIncludeDirExtension(name string) bool {
+	for _, ext := range includeDirExtensions {
+		if path.Ext(name) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// PathErrorCause returns the cause of a `fs.PathError`, as the path is already
+// part of our error messages.
+func pathErrorCause(err error) error {
+	if err, ok := err.(*fs.PathError); ok {
+		return err.Err
+	}
+	return err
+}
@@ -0,0 +1,218 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var includeFS = fstest.MapFS{
+	"app.ini": {Data: []byte("name = app\ninclude = base.ini\n" +
+		"[http]\n!include hosts/local.ini\nport = 80\n!includedir conf.d\n")},
+	"base.ini":         {Data: []byte("[database]\nuser = bob\n")},
+	"hosts/local.ini":  {Data: []byte("host = localhost\n!include ../tls.ini\n")},
+	"tls.ini":          {Data: []byte("[tls]\ncert = cert.pem\n")},
+	"conf.d/a.cnf":     {Data: []byte("a = 1\n")},
+	"conf.d/b.ini":     {Data: []byte("b = 2\n")},
+	"conf.d/c.txt":     {Data: []byte("c = 3\n")},
+	"conf.d/d.ini/x":   {Data: []byte("d = 4\n")},
+	"cycle/a.ini":      {Data: []byte("!include b.ini\n")},
+	"cycle/b.ini":      {Data: []byte("key = value\ninclude = a.ini\n")},
+	"self.ini":         {Data: []byte("include = self.ini\n")},
+	"error.ini":        {Data: []byte("key = value\ninclude = invalid.ini\n")},
+	"invalid.ini":      {Data: []byte("[section]\nkey = value\n\nno separator\n")},
+	"missing.ini":      {Data: []byte("\n!include unknown.ini\n")},
+	"empty.ini":        {Data: []byte("!include \n")},
+	"deep/0.ini":       {Data: []byte("include = 1.ini\n")},
+	"deep/1.ini":       {Data: []byte("include = 2.ini\n")},
+	"deep/2.ini":       {Data: []byte("include = 3.ini\n")},
+	"deep/3.ini":       {Data: []byte("key = value\n")},
+	"twice.ini":        {Data: []byte("include = base.ini\ninclude = base.ini\n")},
+	"root/dir/abs.ini": {Data: []byte("include = /base.ini\n")},
+}
+
+func TestParseFS(t *testing.T) {
+	t.Parallel()
+	got, err := ParseFS(includeFS, "app.ini", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	expected := Config{
+		Global:     {"name": "app"},
+		"database": {"user": "bob"},
+		"http": {
+			"host": "localhost",
+			"port": "80",
+			"a":    "1",
+			"b":    "2",
+		},
+		"tls": {"cert": "cert.pem"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected the config to be %v, but got %v", expected, got)
+	}
+
+	got, err = ParseFS(includeFS, "root/dir/abs.ini", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+	expected = Config{Global: {}, "database": {"user": "bob"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected the config to be %v, but got %v", expected, got)
+	}
+}
+
+func TestParseFSError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{"unknown.ini", Options{}, "ini: error opening: open unknown.ini: file does not exist"},
+		{"cycle/a.ini", Options{}, "ini: syntax error in cycle/b.ini on line 2: " +
+			"include cycle: cycle/a.ini -> cycle/b.ini -> cycle/a.ini"},
+		{"self.ini", Options{}, "ini: syntax error in self.ini on line 1: " +
+			"include cycle: self.ini -> self.ini"},
+		{"error.ini", Options{}, "ini: syntax error in invalid.ini on line 4: " +
+			"no separator found"},
+		{"missing.ini", Options{}, "ini: syntax error in missing.ini on line 2: " +
+			`can't include "unknown.ini": file does not exist`},
+		{"empty.ini", Options{}, "ini: syntax error in empty.ini on line 1: " +
+			"!include path can't be empty"},
+		{"deep/0.ini", Options{MaxIncludeDepth: 2}, "ini: syntax error in deep/2.ini on line 1: " +
+			`can't include "deep/3.ini", includes are nested more than 2 levels deep`},
+		{"twice.ini", Options{}, "ini: syntax error in base.ini on line 1: " +
+			`section "database" already exists`},
+	}
+
+	for _, test := range tests {
+		_, err := ParseFS(includeFS, test.name, test.opts)
+		if err == nil {
+			t.Fatalf("Expected an error parsing %q, but didn't get one", test.name)
+		} else if err.Error() != test.expected {
+			t.Fatalf("Expected error parsing %q to be %q, but got %q",
+				test.name, test.expected, err.Error())
+		}
+	}
+
+	if _, err := ParseFS(includeFS, "deep/0.ini", Options{}); err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+	opts := Options{DuplicateSections: SectionMerge, DuplicateKeys: KeyLastWins}
+	if _, err := ParseFS(includeFS, "twice.ini", opts); err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+}
+
+func TestParseDocumentFS(t *testing.T) {
+	t.Parallel()
+	doc, err := ParseDocumentFS(includeFS, "app.ini", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	content := string(includeFS["app.ini"].Data)
+	if got := doc.String(); got != content {
+		t.Fatalf("Expected Document.String() to return %q, but got %q", content, got)
+	}
+
+	expected, err := ParseFS(includeFS, "app.ini", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+	if got := doc.Config(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Document.Config() to return %v, but got %v", expected, got)
+	}
+
+	var found bool
+	for _, n := range doc.Nodes() {
+		if n.Kind == KeyValueNode && n.Key == "cert" {
+			found = true
+			if n.File != "tls.ini" || n.Line != 2 || n.Section != "tls" {
+				t.Fatalf("Expected the key to be in tls.ini on line 2 in section "+
+					"tls, but got %s on line %d in section %s", n.File, n.Line, n.Section)
+			}
+		} else if n.Kind == IncludeNode && n.Line == 4 {
+			if n.File != "app.ini" || n.Key != "!include" || n.Value != "hosts/local.ini" {
+				t.Fatalf("Unexpected include node %+v", n)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Expected to find the included key")
+	}
+}
+
+func TestDocumentFSChanges(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"app.ini":  {Data: []byte("include = base.ini\n[http]\nport = 80\n")},
+		"base.ini": {Data: []byte("key = 1\n[database]\nuser = bob\n")},
+	}
+	content := string(fsys["app.ini"].Data)
+
+	tests := []struct {
+		change   func(doc *Document) error
+		expected string
+	}{
+		{func(doc *Document) error { return doc.Set(Global, "key", "2") },
+			`ini: can't change key "key" in section "global", it's read from included file "base.ini"`},
+		{func(doc *Document) error { return doc.Set("database", "user", "alice") },
+			`ini: can't change key "user" in section "database", it's read from included file "base.ini"`},
+		{func(doc *Document) error { return doc.Add("database", "host", "localhost") },
+			`ini: can't add a key to section "database", it's read from included file "base.ini"`},
+		{func(doc *Document) error { _, err := doc.Delete(Global, "key"); return err },
+			`ini: can't delete key "key" in section "global", it's read from included file "base.ini"`},
+		{func(doc *Document) error { _, err := doc.DeleteSection("database"); return err },
+			`ini: can't delete section "database", it has lines from included files`},
+		{func(doc *Document) error { _, err := doc.DeleteSection(Global); return err },
+			`ini: can't delete section "global", it has lines from included files`},
+	}
+
+	for _, test := range tests {
+		doc, err := ParseDocumentFS(fsys, "app.ini", Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error parsing document: %s", err.Error())
+		}
+
+		if err := test.change(doc); err == nil || err.Error() != test.expected {
+			t.Fatalf("Expected the error %q, but got %v", test.expected, err)
+		} else if got := doc.String(); got != content {
+			t.Fatalf("Expected the document to be unchanged, but got %q", got)
+		} else if value, _ := doc.Get(Global, "key"); value != "1" {
+			t.Fatalf("Expected the included key to be unchanged, but got %q", value)
+		}
+	}
+
+	// Changes to the file itself are written, and parsed back into the same
+	// configuration.
+	opts := Options{DuplicateSections: SectionMerge}
+	doc, err := ParseDocumentFS(fsys, "app.ini", opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+	setValue(t, doc, Global, "name", "app")
+	setValue(t, doc, "http", "port", "8080")
+	setValue(t, doc, "database", "host", "localhost")
+	if found, err := doc.Delete("http", "port"); err != nil || !found {
+		t.Fatalf("Expected Document.Delete to find the key, but got %v and %v", found, err)
+	}
+	expected := "include = base.ini\nname=app\n[http]\n\n[database]\nhost=localhost\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+
+	fsys["app.ini"] = &fstest.MapFile{Data: []byte(expected)}
+	got, err := ParseFS(fsys, "app.ini", opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing changed document: %s", err.Error())
+	} else if expected := doc.Config(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected the config to be %v, but got %v", expected, got)
+	}
+}
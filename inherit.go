@@ -0,0 +1,160 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Separator between the section and its parent in a section header, see
+// Options.InheritSections.
+const parentSeparator = ":"
+
+// SectionParent is the parent of a section and the position of the section
+// header that declared it.
+type sectionParent struct {
+	parent     string
+	file       string
+	lineNumber int
+}
+
+// SetParent sets the parent of the section, if any.
+func (p *parser) setParent(section, parent string, lineNumber int) error {
+	if parent == "" {
+		return nil
+	}
+
+	if p.parents == nil {
+		p.parents = map[string]sectionParent{}
+	}
+	if existing, ok := p.parents[section]; ok && existing.parent != parent {
+		return fmt.Errorf("section %q already extends section %q", section, existing.parent)
+	}
+	p.parents[section] = sectionParent{parent, p.file(), lineNumber}
+	return nil
+}
+
+// Inherit checks the parents of all sections and adds the inherited keys to
+// the sections in the configuration.
+func (p *parser) inherit() error {
+	sections := make([]string, 0, len(p.parents))
+	parents := make(map[string]string, len(p.parents))
+	for section, sp := range p.parents {
+		sections = append(sections, section)
+		parents[section] = p.sectionName(sp.parent)
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		sp := p.parents[section]
+		if _, ok := p.Config[parents[section]]; !ok {
+			err := p.recover(SyntaxError{
+				File:       sp.file,
+				LineNumber: sp.lineNumber,
+				Message:    fmt.Sprintf("unknown parent section %q", sp.parent),
+			})
+			if err != nil {
+				return err
+			}
+			delete(parents, section)
+		}
+	}
+
+	for _, section := range sections {
+		if chain, cycle := parentChain(parents, section); cycle != -1 {
+			chain = append(chain[cycle:], chain[cycle])
+			sp := p.parents[chain[0]]
+			err := p.recover(SyntaxError{
+				File:       sp.file,
+				LineNumber: sp.lineNumber,
+				Message:    "section inheritance cycle: " + strings.Join(chain, " -> "),
+			})
+			if err != nil {
+				return err
+			}
+			// Break the cycle.
+			delete(parents, chain[0])
+		}
+	}
+
+	p.opts.inheritKeys(p.Config, parents, p.sectionName(p.opts.DefaultSection))
+	return nil
+}
+
+// ParentChain returns the section followed by its parent, the parent of the
+// parent, etc. If the section (indirectly) extends itself it also returns the
+// index in the chain of the first section in the cycle, otherwise -1.
+func parentChain(parents map[string]string, section string) ([]string, int) {
+	chain := []string{section}
+	for {
+		parent, ok := parents[section]
+		if !ok {
+			return chain, -1
+		}
+		for i, s := range chain {
+			if s == parent {
+				return chain, i
+			}
+		}
+		chain = append(chain, parent)
+		section = parent
+	}
+}
+
+// LookupChain returns the sections in which keys of the section are looked
+// up, in order of precedence: the section itself, its parents and the default
+// section.
+func lookupChain(parents map[string]string, defaultSection, section string) []string {
+	chain, _ := parentChain(parents, section)
+	if section != Global && defaultSection != "" && !contains(chain, defaultSection) {
+		chain = append(chain, defaultSection)
+	}
+	return chain
+}
+
+// InheritKeys adds the keys that the sections in the configuration inherit to
+// the sections, see lookupChain. The parents and default section must use the
+// names of the sections in the configuration.
+func (o *Options) inheritKeys(c Config, parents map[string]string, defaultSection string) {
+	if len(parents) == 0 && defaultSection == "" {
+		return
+	}
+
+	// Copy the keys defined in the sections themselves first, so that keys
+	// inherited by a parent don't take precedence over keys of an ancestor.
+	own := make(Config, len(c))
+	for name, section := range c {
+		own[name] = make(Section, len(section))
+		for key, value := range section {
+			own[name][key] = value
+		}
+	}
+
+	for name, section := range c {
+		keys := make(map[string]bool, len(section))
+		for key := range section {
+			keys[o.foldKey(key)] = true
+		}
+		for _, ancestor := range lookupChain(parents, defaultSection, name)[1:] {
+			for key, value := range own[ancestor] {
+				if !keys[o.foldKey(key)] {
+					keys[o.foldKey(key)] = true
+					section[key] = value
+				}
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, element := range list {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
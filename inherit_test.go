@@ -0,0 +1,154 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const inheritContent = `name = app
+[DEFAULT]
+timeout = 10
+host = default
+
+[base]
+host = localhost
+port = 8080
+
+[staging : base]
+port = 8081
+
+[prod : staging]
+host = example.com
+
+[other]
+`
+
+var inheritOptions = Options{DefaultSection: "DEFAULT", InheritSections: true}
+
+func TestParseInherit(t *testing.T) {
+	t.Parallel()
+	got, err := ParseWithOptions(strings.NewReader(inheritContent), inheritOptions)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	expected := Config{
+		Global:    {"name": "app"},
+		"DEFAULT": {"timeout": "10", "host": "default"},
+		"base":    {"timeout": "10", "host": "localhost", "port": "8080"},
+		"staging": {"timeout": "10", "host": "localhost", "port": "8081"},
+		"prod":    {"timeout": "10", "host": "example.com", "port": "8081"},
+		"other":   {"timeout": "10", "host": "default"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, but got %v", expected, got)
+	}
+
+	doc, err := ParseDocumentWithOptions(strings.NewReader(inheritContent), inheritOptions)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+	if got := doc.Config(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Document.Config() to return %v, but got %v", expected, got)
+	}
+	for section, keys := range expected {
+		for key, value := range keys {
+			if got, ok := doc.Get(section, key); !ok || got != value {
+				t.Fatalf("Expected Document.Get(%q, %q) to return %q, but got %q",
+					section, key, value, got)
+			}
+		}
+	}
+	if got := doc.String(); got != inheritContent {
+		t.Fatalf("Expected Document.String() to return %q, but got %q", inheritContent, got)
+	}
+
+	// Without the options the header is the name of the section.
+	got, err = Parse(strings.NewReader("[prod : base]\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	} else if _, ok := got["prod : base"]; !ok {
+		t.Fatalf("Expected the section to be named %q, but got %v", "prod : base", got)
+	}
+
+	// Quoted section names can contain the separator.
+	got, err = ParseWithOptions(strings.NewReader("[base]\nkey=value\n[\"a:b\" : base] ; comment\n"), inheritOptions)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	} else if value := got["a:b"]["key"]; value != "value" {
+		t.Fatalf("Expected section %q to inherit the key, but got %v", "a:b", got)
+	}
+}
+
+func TestParseInheritError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"[a : b]", `ini: syntax error on line 1: unknown parent section "b"`},
+		{"[a : a]", "ini: syntax error on line 1: section inheritance cycle: a -> a"},
+		{"[x : a]\n[a : b]\n[b : c]\n[c : a]",
+			"ini: syntax error on line 2: section inheritance cycle: a -> b -> c -> a"},
+		{"[a : ]", "ini: syntax error on line 1: parent section can't be empty"},
+		{"[ : a]", "ini: syntax error on line 1: section can't be empty"},
+		{`["a" : ]`, "ini: syntax error on line 1: parent section can't be empty"},
+		{"[a]\n[b]\n[c : a]\n[c : b]",
+			`ini: syntax error on line 4: section "c" already extends section "a"`},
+	}
+
+	opts := Options{InheritSections: true, DuplicateSections: SectionMerge}
+	for _, test := range tests {
+		_, err := ParseWithOptions(strings.NewReader(test.content), opts)
+		if err == nil {
+			t.Fatalf("Expected an error parsing %q, but didn't get one", test.content)
+		} else if err.Error() != test.expected {
+			t.Fatalf("Expected error parsing %q to be %q, but got %q",
+				test.content, test.expected, err.Error())
+		}
+	}
+}
+
+type inheritTestData struct {
+	Name string
+	Prod struct {
+		Host    string
+		Port    int
+		Timeout int
+	}
+}
+
+func TestDecodeInherit(t *testing.T) {
+	t.Parallel()
+	c, err := ParseWithOptions(strings.NewReader(inheritContent), inheritOptions)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(inheritContent), inheritOptions)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	var expected inheritTestData
+	expected.Name = "app"
+	expected.Prod.Host = "example.com"
+	expected.Prod.Port = 8081
+	expected.Prod.Timeout = 10
+
+	var got, gotDoc inheritTestData
+	if err := c.Decode(&got); err != nil {
+		t.Fatalf("Unexpected error decoding: %s", err.Error())
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %+v, but got %+v", expected, got)
+	}
+	if err := doc.Decode(&gotDoc); err != nil {
+		t.Fatalf("Unexpected error decoding document: %s", err.Error())
+	} else if !reflect.DeepEqual(gotDoc, expected) {
+		t.Fatalf("Expected %+v, but got %+v", expected, gotDoc)
+	}
+}
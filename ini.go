@@ -13,7 +13,6 @@ import (
 	"io"
 	"reflect"
 	"sort"
-	"strconv"
 	"strings"
 	"unicode"
 )
@@ -41,50 +40,69 @@ const globalName = "global"
 //
 //	value := config[ini.Global]["key"]
 //	value2, found := config[ini.Global]["key2"]
+//
+// Ranging over a Config, or a Section, is done in random order, as with any
+// map. Use a Document to get the sections and keys in the order of the file,
+// see Document.Sections and Document.Keys.
 type Config map[string]Section
 
 // Section holds the key value pairs inside a configuration.
 //
 //	section := config["section"]
 //	value, found := section["key"]
+//
+// A Section holds a single value per key, the last one for a repeated key. Use
+// Metadata.Values, see ParseWithMetadata, or Document.Values for all values of
+// a repeated key.
 type Section map[string]string
 
 // String returns an ini formatted configuration, ready to be written to a file.
+// Unlike WriteTo it doesn't check if the configuration can be written.
 func (c *Config) String() string {
 	return c.buffer().String()
 }
 
 // Bytes returns an ini formatted configuration, ready to be written to a file.
+// Unlike WriteTo it doesn't check if the configuration can be written.
 func (c *Config) Bytes() []byte {
 	return c.buffer().Bytes()
 }
 
-// WriteTo writes the configuration to the writer in the ini format.
+// WriteTo writes the configuration to the writer in the ini format, encoded in
+// UTF-8 with LF line endings. Use an Encoder to write it in another encoding or
+// with other line endings, e.g. those of the input, see Metadata.Encoding and
+// Metadata.LineEnding.
+//
+// The output is guaranteed to be parsed back by Parse into the same
+// configuration, where a missing or nil section is parsed as an empty one.
+// Section names are quoted if needed, e.g. `["weird ] name"]`. If the
+// configuration can't be written like that, which is only the case for an
+// empty key, an error is returned and nothing is written.
 func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	if err := c.check(); err != nil {
+		return 0, err
+	}
 	return c.buffer().WriteTo(w)
 }
 
-// Buffer creates a `bytes.Buffer` with an ini formatted configuration.
-func (c *Config) buffer() *bytes.Buffer {
-	var result bytes.Buffer
-
-	sections := getConfigSectionsAlpha(*c)
-	for _, sectionName := range sections {
-		if sectionName != Global {
-			result.WriteString("[" + sectionName + "]\n")
-		}
-
-		section := (*c)[sectionName]
-		keys := getSectionKeysAlpha(section)
-		for _, key := range keys {
-			value := strconv.Quote(section[key])
-			key = strconv.Quote(key)
-			result.WriteString(key + "=" + value + "\n")
+// Check checks if the configuration can be written so that it's parsed back
+// into the same configuration, see WriteTo.
+func (c Config) check() error {
+	for _, sectionName := range getConfigSectionsAlpha(c) {
+		if _, ok := c[sectionName][""]; ok {
+			if sectionName == Global {
+				sectionName = globalName
+			}
+			return fmt.Errorf("ini: can't write an empty key in section %q", sectionName)
 		}
-		result.WriteString("\n")
 	}
+	return nil
+}
 
-	return &result
+// Buffer creates a `bytes.Buffer` with an ini formatted configuration.
+func (c *Config) buffer() *bytes.Buffer {
+	var e Encoder
+	return e.configBuffer(*c, nil)
 }
 
 // GetConfigSectionsAlpha sorts the sections alphabetically with the global
@@ -110,17 +128,20 @@ func getSectionKeysAlpha(m Section) []string {
 	return keys
 }
 
-type fieldCombo struct {
-	value reflect.Value
-	field reflect.StructField
-}
-
 // Decode decodes a configuration into a struct. Any properties to be set need
 // to be public. Keys are renamed, whitespace is removed and keys start with a
 // capaital, like so:
 //
 //	"my key" -> "MyKey"
 //
+// Keys and sections are matched case-insensitively, so "MY_KEY" is decoded
+// into MyKey as well. The spellings of the name take precedence, in the order
+// "mykey", "MyKey", "my_key", "My_Key", "my-key", "My-Key", "my key" and
+// "My Key", or "Key" and "key" for a single word. If none of them is used the
+// first key in the file that matches case-insensitively is used. A Config
+// doesn't know the order of the file, so if more than one of its keys matches
+// an error is returned.
+//
 // Tags are supported to define the key of a configuration. See below for a
 // small example, for more examples see the tags example in the _examples
 // directory.
@@ -129,6 +150,22 @@ type fieldCombo struct {
 //		AppName `ini:"name"`
 //	}
 //
+// Fields with the tag `ini:"-"` are skipped.
+//
+// Struct fields are decoded from sections, struct fields inside those from
+// subsections, at any depth, see SplitSection. For example the following
+// struct is decoded from the section "[database]" and its subsection
+// "[database.replica]" or "[database "replica"]".
+//
+//	struct {
+//		Database struct {
+//			Host    string
+//			Replica struct {
+//				Host string
+//			}
+//		}
+//	}
+//
 // Slices are supported by using a comma separated list, like so:
 //
 //	"string1, string2" -> []string{"string1", "string2"}
@@ -139,6 +176,9 @@ type fieldCombo struct {
 //	"1, t, T, TRUE, true, True" -> true
 //	"0, f, F, FALSE, false, False" -> false
 //
+// Document.Decode and Metadata.Decode also accept "yes", "on", "no" and "off"
+// if the options allow it, see Options.ExtendedBools.
+//
 // Time is supported with the following formats:
 //
 //	"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05", time.RFC3339,
@@ -146,9 +186,71 @@ type fieldCombo struct {
 //
 // Duration is also supported, see `time.ParseDuration` for the documentation.
 //
+// A Config only holds the last value of a repeated key, see KeyCollectAll, so
+// only that value is decoded. Use Metadata.Decode, see ParseWithMetadata, or
+// Document.Decode to decode all values of a repeated key into a slice.
+//
+// If a value can't be decoded a DecodeError is returned, which holds the
+// section, key, struct field and value. A Config doesn't hold the positions of
+// its keys, so the position in the error is unknown. Use Metadata.Decode, see
+// ParseWithMetadata, Decode or Document.Decode for errors that include the
+// position.
+//
 // Note: underneath Decode uses the reflect package which isn't great for
 // performance, so use it with care.
 func (c *Config) Decode(dst interface{}) error {
+	return decode(*c, dst, "Config.Decode", false)
+}
+
+// Source is a source of configuration values to decode.
+type source interface {
+	// Sections returns the names of all sections.
+	sections() []string
+	// HasKeys checks if the section has any keys.
+	hasKeys(section string) bool
+	// Keys returns the keys in the section, in the order of the source if
+	// it's ordered, otherwise sorted alphabetically.
+	keys(section string) []string
+	// Ordered checks if the source knows the order of the keys.
+	ordered() bool
+	// Values returns all values of the key in the section, or nil if the key
+	// doesn't exist.
+	values(section, key string) []string
+	// Position returns the position of the key in the section, if known.
+	position(section, key string) Position
+}
+
+func (c Config) sections() []string {
+	return getConfigSectionsAlpha(c)
+}
+
+func (c Config) hasKeys(section string) bool {
+	return len(c[section]) != 0
+}
+
+func (c Config) keys(section string) []string {
+	return getSectionKeysAlpha(c[section])
+}
+
+func (c Config) ordered() bool {
+	return false
+}
+
+func (c Config) position(section, key string) Position {
+	return Position{}
+}
+
+func (c Config) values(section, key string) []string {
+	if value, ok := c[section][key]; ok {
+		return []string{value}
+	}
+	return nil
+}
+
+// Decode decodes the configuration from src into dst, see Config.Decode. Name
+// is the name of the calling function, used in error messages. ExtendedBools
+// is Options.ExtendedBools.
+func decode(src source, dst interface{}, name string, extendedBools bool) error {
 	valuePtr := reflect.ValueOf(dst)
 	value := reflect.Indirect(valuePtr)
 
@@ -156,83 +258,207 @@ func (c *Config) Decode(dst interface{}) error {
 	// struct we can't set/change any keys on it, in either case we can't do
 	// anything with the value.
 	if valuePtr.Kind() != reflect.Ptr || value.Kind() != reflect.Struct {
-		return errors.New("ini: Config.Decode requires a pointer to a struct")
+		return errors.New("ini: " + name + " requires a pointer to a struct")
+	}
+
+	d := decoder{
+		src:           src,
+		extendedBools: extendedBools,
+		sections:      map[string][]string{},
+		keys:          map[string][]string{},
+	}
+	for _, section := range src.sections() {
+		key := strings.ToLower(pathKey(SplitSection(section)))
+		d.sections[key] = append(d.sections[key], section)
+	}
+	return d.decodeStruct(value, [][]string{nil}, "")
+}
+
+type decoder struct {
+	src source
+	// See Options.ExtendedBools.
+	extendedBools bool
+	// Section names by the lower case key of their path, see pathKey.
+	sections map[string][]string
+	// Keys of the sections, see source.keys.
+	keys map[string][]string
+}
+
+// DecodeStruct decodes the struct from the sections with one of the paths.
+// FieldPath is the path to the struct, used in errors.
+func (d *decoder) decodeStruct(value reflect.Value, paths [][]string, fieldPath string) error {
+	var sectionNames []string
+	for _, path := range paths {
+		sectionNames = append(sectionNames, d.sectionNames(path)...)
 	}
 
-	dstType := value.Type()
+	valueType := value.Type()
 	for i := value.NumField() - 1; i >= 0; i-- {
 		field := value.Field(i)
 		if !field.IsValid() || !field.CanSet() {
 			continue
 		}
 
-		strField := dstType.Field(i)
-		var fields []fieldCombo
-		var sectionNames = []string{""}
+		structField := valueType.Field(i)
+		if structField.Tag.Get("ini") == skipTag {
+			continue
+		}
+		fieldName := structField.Name
+		if fieldPath != "" {
+			fieldName = fieldPath + "." + fieldName
+		}
 
-		structFieldType := field.Type()
-		if kind := field.Kind(); kind == reflect.Struct &&
-			structFieldType != typeDuration && structFieldType != typeTime {
-			sectionName := strField.Tag.Get("ini")
-			if sectionName != "" {
-				sectionNames[0] = sectionName
+		if isSection(field) {
+			var names [][]string
+			if tag := structField.Tag.Get("ini"); tag != "" {
+				names = [][]string{SplitSection(tag)}
 			} else {
-				sectionNames = possibleNames(strField.Name)
+				// Section names are compared case-insensitively, see
+				// sectionNames, so only one name per case is needed.
+				seen := map[string]bool{}
+				for _, name := range possibleNames(structField.Name) {
+					if !seen[strings.ToLower(name)] {
+						seen[strings.ToLower(name)] = true
+						names = append(names, []string{name})
+					}
+				}
 			}
 
-			for i := field.NumField() - 1; i >= 0; i-- {
-				structField := field.Field(i)
-				if !structField.IsValid() || !structField.CanSet() {
-					continue
+			var subPaths [][]string
+			for _, path := range paths {
+				for _, name := range names {
+					subPath := append(path[:len(path):len(path)], name...)
+					subPaths = append(subPaths, subPath)
 				}
-				structFieldType := structFieldType.Field(i)
-				fields = append(fields, fieldCombo{structField, structFieldType})
 			}
+
+			if err := d.decodeStruct(field, subPaths, fieldName); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var keys []string
+		if key := structField.Tag.Get("ini"); key != "" {
+			keys = []string{key}
 		} else {
-			fields = []fieldCombo{{field, strField}}
+			keys = possibleNames(structField.Name)
 		}
 
-		for _, combo := range fields {
-			value := combo.value
-			field := combo.field
+		if err := d.trySetReflect(sectionNames, keys, field, fieldName); err != nil {
+			return err
+		}
+	}
 
-			var keys []string
-			if key := field.Tag.Get("ini"); key != "" {
-				keys = []string{key}
-			} else {
-				keys = possibleNames(field.Name)
-			}
+	return nil
+}
 
-			if err := c.trySetReflect(sectionNames, keys, value); err != nil {
-				return err
+// IsSection checks if the value should be decoded from a section, rather than
+// a single value.
+func isSection(value reflect.Value) bool {
+	t := value.Type()
+	return value.Kind() == reflect.Struct && t != typeDuration && t != typeTime
+}
+
+// SectionNames returns the names of the sections with the path, compared
+// case-insensitively. Sections that match the path exactly come first.
+func (d *decoder) sectionNames(path []string) []string {
+	key := pathKey(path)
+	var exact, folded []string
+	for _, name := range d.sections[strings.ToLower(key)] {
+		if pathKey(SplitSection(name)) == key {
+			exact = append(exact, name)
+		} else {
+			folded = append(folded, name)
+		}
+	}
+	return append(exact, folded...)
+}
+
+// FindKey returns the first of the keys that's in the section. Keys are first
+// compared exactly, then case-insensitively, in which case the first matching
+// key in the source is used. If the source isn't ordered and more than one key
+// matches case-insensitively an error is returned. FieldName is the path to
+// the field, used in errors.
+func (d *decoder) findKey(sectionName string, keys []string, fieldName string) (string, bool, error) {
+	sectionKeys, ok := d.keys[sectionName]
+	if !ok {
+		sectionKeys = d.src.keys(sectionName)
+		d.keys[sectionName] = sectionKeys
+	}
+
+	for _, key := range keys {
+		if contains(sectionKeys, key) {
+			return key, true, nil
+		}
+	}
+
+	var found string
+	for _, sectionKey := range sectionKeys {
+		if !matchesFold(sectionKey, keys) {
+			continue
+		} else if d.src.ordered() {
+			return sectionKey, true, nil
+		} else if found != "" {
+			if sectionName == Global {
+				sectionName = globalName
 			}
+			return "", false, fmt.Errorf("ini: can't decode %s, keys %q and %q in section %q both match it case-insensitively",
+				fieldName, found, sectionKey, sectionName)
 		}
+		found = sectionKey
 	}
+	return found, found != "", nil
+}
 
-	return nil
+// MatchesFold checks if the key matches one of the keys case-insensitively.
+func matchesFold(key string, keys []string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
 }
 
 // TrySetReflect tries the givens section and keys combination to get the value
-// from the config and then sets the field if a value if found.
-func (c *Config) trySetReflect(sectionNames []string, keys []string, keyValue reflect.Value) error {
+// from the source and then sets the field if a value if found. FieldName is the
+// path to the field, used in errors.
+func (d *decoder) trySetReflect(sectionNames []string, keys []string, keyValue reflect.Value, fieldName string) error {
+	src := d.src
 	for _, sectionName := range sectionNames {
-		section := (*c)[sectionName]
-		if len(section) == 0 {
+		if !src.hasKeys(sectionName) {
 			continue
 		}
 
-		for _, key := range keys {
-			value, ok := section[key]
-			if !ok {
-				continue
+		key, ok, err := d.findKey(sectionName, keys, fieldName)
+		if err != nil {
+			return err
+		} else if ok {
+			values := src.values(sectionName, key)
+
+			// Multiple values are only supported by slices, each value being an
+			// element, for anything else we use the last value.
+			value := values[len(values)-1]
+			var err error
+			if keyValue.Kind() == reflect.Slice && len(values) > 1 {
+				if err = setSliceValues(&keyValue, values, d.extendedBools); err != nil {
+					value = invalidValue(keyValue.Type(), values, d.extendedBools)
+				}
+			} else {
+				err = setReflectValue(&keyValue, value, d.extendedBools)
 			}
 
-			if err := setReflectValue(&keyValue, value); err != nil {
-				if sectionName == Global {
-					sectionName = globalName
+			if err != nil {
+				return DecodeError{
+					Section:  sectionName,
+					Key:      key,
+					Field:    fieldName,
+					Value:    value,
+					Type:     keyValue.Type(),
+					Position: src.position(sectionName, key),
+					Err:      err,
 				}
-				return fmt.Errorf("ini: error decoding %q in section %q: %s",
-					key, sectionName, err.Error())
 			}
 
 			return nil
@@ -244,29 +470,21 @@ func (c *Config) trySetReflect(sectionNames []string, keys []string, keyValue re
 
 var separators = []string{"", "_", "-", " "}
 
-// PossibleNames generates possible names for a given name. It splits up the
-// given name on upper case and underscores (see getNameParts) and then joins
-// them using nothing (""), underscores, dashes and spaces (see separators
-// variable).
+// PossibleNames generates possible names for a given name, in order of
+// precedence. It splits up the given name on upper case and underscores (see
+// getNameParts) and then joins them using nothing (""), underscores, dashes
+// and spaces (see separators variable), each in lower case and as is.
 func possibleNames(name string) []string {
 	nameParts := getNameParts(name)
 	if len(nameParts) == 1 {
 		return []string{name, strings.ToLower(name)}
 	}
 
-	var names []string
+	names := make([]string, 0, 2*len(separators))
 	for _, separator := range separators {
-		var newName string
-		for _, namePart := range nameParts {
-			newName += namePart
-			newName += separator
-		}
-		newName = newName[:len(newName)-len(separator)]
-
-		names = append(names, strings.ToLower(newName))
-		names = append(names, newName)
+		joined := strings.Join(nameParts, separator)
+		names = append(names, strings.ToLower(joined), joined)
 	}
-
 	return names
 }
 
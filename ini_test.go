@@ -6,9 +6,11 @@ package ini
 
 import (
 	"bytes"
+	"math/rand"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/quick"
 )
 
 func TestGetSectionKeysAlpha(t *testing.T) {
@@ -103,3 +105,205 @@ func TestComplete(t *testing.T) {
 			"return the same string, but got: \n%q, \n%q and \n%q", gotString, gotBytes, got)
 	}
 }
+
+func TestConfigSectionQuoting(t *testing.T) {
+	t.Parallel()
+	c := Config{
+		Global:            {},
+		"plain":           {},
+		"weird ] name":    {},
+		" spaces ":        {},
+		"new\nline":       {},
+		`remote "origin"`: {},
+		`"quoted"`:        {},
+		`"my.app".sub`:    {},
+	}
+
+	expected := "\n" + `[" spaces "]` + "\n\n" + `["my.app".sub]` + "\n\n" +
+		`["\"quoted\""]` + "\n\n" + `["new\nline"]` + "\n\n[plain]\n\n" +
+		`[remote "origin"]` + "\n\n" + `["weird ] name"]` + "\n\n"
+	if got := c.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+
+	got, err := Parse(strings.NewReader(expected))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing config: %s", err.Error())
+	} else if !reflect.DeepEqual(got, c) {
+		t.Fatalf("Expected %q, but got %q", c, got)
+	}
+}
+
+func TestConfigEscapes(t *testing.T) {
+	t.Parallel()
+	c := Config{
+		Global: {
+			"new\nline": "new\nline",
+			"tab":       "\ttab\t",
+			"quotes":    `"double" and 'single'`,
+			"unicode":   "café \u2028 \U0001F600",
+			"control":   "\x00\x01\x7f",
+			"invalid":   "\xff\xfe",
+			"backslash": `C:\new\dir\`,
+		},
+	}
+
+	got, err := Parse(bytes.NewReader(c.Bytes()))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing config: %s", err.Error())
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Fatalf("Expected %q, but got %q", c, got)
+	}
+}
+
+// RoundTripChars are the characters used to generate configurations in the
+// round trip tests, chosen to have a special meaning in the ini format.
+var roundTripChars = []string{
+	"a", "Z", "0", " ", "\t", "\n", "\r", "\v", "\x00", "\x7f", "\xff", "=", ":",
+	";", "#", "[", "]", `"`, "'", "\\", "é", "€", "\u00a0", "\u2028", "\ufeff",
+	"\U0001F600",
+}
+
+// RoundTripConfig is a Config that can be generated by `testing/quick`.
+type roundTripConfig Config
+
+func (roundTripConfig) Generate(rand *rand.Rand, size int) reflect.Value {
+	str := func() string {
+		var s strings.Builder
+		for n := rand.Intn(size + 1); n > 0; n-- {
+			s.WriteString(roundTripChars[rand.Intn(len(roundTripChars))])
+		}
+		return s.String()
+	}
+
+	c := roundTripConfig{}
+	for n := rand.Intn(4); n >= 0; n-- {
+		name := str()
+		if n == 0 {
+			name = Global
+		}
+		section := Section{}
+		for m := rand.Intn(size + 1); m > 0; m-- {
+			section[str()] = str()
+		}
+		c[name] = section
+	}
+	return reflect.ValueOf(c)
+}
+
+// RoundTripEncoders are the encoders used in the round trip tests.
+var roundTripEncoders = []func(enc *Encoder){
+	func(enc *Encoder) {},
+	func(enc *Encoder) {
+		enc.MinimalQuoting = true
+		enc.SpaceAroundSeparator = true
+		enc.AlignSeparators = true
+		enc.Header = "Header with \\ and \"quotes\".\n\n[section]"
+	},
+}
+
+// CheckRoundTrip checks that the configuration is either written by all
+// encoders and parsed back into the same configuration, or that all return an
+// error. It returns the error.
+func checkRoundTrip(t *testing.T, c Config) error {
+	expected := Config{Global: {}}
+	for name, section := range c {
+		expected[name] = Section{}
+		for key, value := range section {
+			expected[name][key] = value
+		}
+	}
+
+	var buf bytes.Buffer
+	_, writeErr := c.WriteTo(&buf)
+	if writeErr == nil {
+		if got, err := Parse(&buf); err != nil {
+			t.Fatalf("Unexpected error parsing the output of %#v: %s", c, err.Error())
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("Expected %#v, but got %#v", expected, got)
+		}
+	}
+
+	for _, setup := range roundTripEncoders {
+		buf.Reset()
+		enc := NewEncoder(&buf)
+		setup(enc)
+		if err := enc.Encode(c); (err == nil) != (writeErr == nil) {
+			t.Fatalf("Expected the encoder to return the error %v, but got %v", writeErr, err)
+		} else if err != nil {
+			continue
+		}
+
+		if got, err := Parse(&buf); err != nil {
+			t.Fatalf("Unexpected error parsing %q: %s", buf.String(), err.Error())
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("Expected %#v, but got %#v", expected, got)
+		}
+	}
+	return writeErr
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	var written int
+	f := func(c roundTripConfig) bool {
+		if checkRoundTrip(t, Config(c)) == nil {
+			written++
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	} else if written == 0 {
+		t.Fatal("Expected some configurations to be written, but all returned an error")
+	}
+}
+
+func TestRoundTripErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		config   Config
+		expected string
+	}{
+		{Config{Global: {"": "value"}}, `ini: can't write an empty key in section "global"`},
+		{Config{"section": {"": ""}}, `ini: can't write an empty key in section "section"`},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if _, err := test.config.WriteTo(&buf); err == nil || err.Error() != test.expected {
+			t.Fatalf("Expected the error %q, but got %v", test.expected, err)
+		} else if buf.Len() != 0 {
+			t.Fatalf("Expected nothing to be written, but got %q", buf.String())
+		}
+		if checkRoundTrip(t, test.config) == nil {
+			t.Fatalf("Expected an error writing %#v", test.config)
+		}
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("section", "key", "value")
+	f.Add("", "new\nline", " \"quoted\" ")
+	f.Add("a.b \"c\"", "key=value", "; comment \\")
+	f.Add("[nested]", "[key]", "\xff\x00")
+	f.Fuzz(func(t *testing.T, section, key, value string) {
+		checkRoundTrip(t, Config{section: {key: value}})
+	})
+}
+
+func FuzzParseWrite(f *testing.F) {
+	f.Add("key = value\n[section]\nkey = \"value\" ; comment\n")
+	f.Add("multi = first \\\n  second\nindented = first\n  second\n")
+	f.Add("[a.b]\nkey = 'single'\n[a \"b\"]\n\"#\" = \\;\n")
+	f.Fuzz(func(t *testing.T, input string) {
+		c, err := Parse(strings.NewReader(input))
+		if err != nil {
+			return
+		}
+		if err := checkRoundTrip(t, c); err != nil {
+			t.Fatalf("Unexpected error writing the parsed configuration %#v: %s", c, err.Error())
+		}
+	})
+}
@@ -0,0 +1,258 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Interpolation is the syntax used to reference other values, see
+// Config.Interpolate.
+type Interpolation uint8
+
+// The supported interpolation syntaxes.
+const (
+	// ExtendedInterpolation references keys with "${key}" or
+	// "${section.key}" and environment variables with "${env:NAME}" or
+	// "${env:NAME:-default}". A literal dollar sign is written as "$$".
+	ExtendedInterpolation Interpolation = iota
+	// BasicInterpolation references keys with "%(key)s", like Python's
+	// configparser. A literal percent sign is written as "%%".
+	BasicInterpolation
+)
+
+// Prefix of references to environment variables and the separator of the
+// default value, used in extended interpolation.
+const (
+	envPrefix  = "env:"
+	envDefault = ":-"
+)
+
+// InterpolateOptions changes the way values are interpolated, see
+// Config.Interpolate. The zero value uses extended interpolation and the
+// environment of the process.
+type InterpolateOptions struct {
+	// Syntax is the interpolation syntax, defaults to ExtendedInterpolation.
+	Syntax Interpolation
+
+	// LookupEnv looks up environment variables, defaults to `os.LookupEnv`.
+	LookupEnv func(name string) (string, bool)
+}
+
+// Interpolate returns a copy of the configuration in which references to
+// other values are replaced with those values, leaving the configuration
+// itself unchanged.
+//
+//	[paths]
+//	base = /srv/app
+//	logs = ${base}/logs
+//	tmp = ${env:TMPDIR:-/tmp}
+//
+//	[http]
+//	root = ${paths.base}/public
+//
+// A reference without a section, e.g. "${base}", refers to a key in the same
+// section, or if it isn't defined there to a key in the global section. A
+// reference with a section, e.g. "${paths.base}", refers to the key after the
+// last dot in the section before it, "${.key}" refers to the global section.
+// The default value of an environment variable is used if the variable isn't
+// set or is empty, referencing an unset variable without a default is an
+// error.
+//
+// With BasicInterpolation "%(key)s" refers to a key in the same section or in
+// the global section, other sections can't be referenced.
+//
+// Referenced values are interpolated as well. A reference to an unknown key or
+// a value that (indirectly) references itself results in an error, which
+// includes the chain of references that lead to it.
+func (c Config) Interpolate(opts InterpolateOptions) (Config, error) {
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+
+	in := interpolator{config: c, result: make(Config, len(c)), opts: opts}
+	for sectionName := range c {
+		in.result[sectionName] = make(Section, len(c[sectionName]))
+	}
+
+	for _, sectionName := range getConfigSectionsAlpha(c) {
+		for _, key := range getSectionKeysAlpha(c[sectionName]) {
+			if _, err := in.resolve(sectionName, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return in.result, nil
+}
+
+// Reference is a reference to a key in a section.
+type reference struct {
+	section, key string
+}
+
+// String returns the reference as it would be written in extended
+// interpolation.
+func (r reference) String() string {
+	if r.section == Global {
+		return r.key
+	}
+	return r.section + "." + r.key
+}
+
+type interpolator struct {
+	config, result Config
+	opts           InterpolateOptions
+
+	// The chain of keys being interpolated, used to detect cycles.
+	chain []reference
+}
+
+// Resolve returns the interpolated value of the key in the section, which
+// must exist.
+func (in *interpolator) resolve(sectionName, key string) (string, error) {
+	if value, ok := in.result[sectionName][key]; ok {
+		return value, nil
+	}
+
+	ref := reference{sectionName, key}
+	for i, r := range in.chain {
+		if r == ref {
+			return "", fmt.Errorf("ini: interpolation cycle: %s",
+				formatChain(append(in.chain[i:len(in.chain):len(in.chain)], ref)))
+		}
+	}
+
+	in.chain = append(in.chain, ref)
+	var value string
+	var err error
+	if in.opts.Syntax == BasicInterpolation {
+		value, err = in.expandBasic(sectionName, in.config[sectionName][key])
+	} else {
+		value, err = in.expandExtended(sectionName, in.config[sectionName][key])
+	}
+	in.chain = in.chain[:len(in.chain)-1]
+	if err != nil {
+		return "", err
+	}
+
+	in.result[sectionName][key] = value
+	return value, nil
+}
+
+func (in *interpolator) expandExtended(sectionName, value string) (string, error) {
+	var result strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 >= len(value) {
+			result.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			result.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end == -1 {
+				return "", in.errorf("unclosed reference %q", value[i:])
+			}
+			name := value[i+2 : i+2+end]
+			replacement, err := in.lookupExtended(sectionName, name)
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(replacement)
+			i += 2 + end
+		default:
+			result.WriteByte('$')
+		}
+	}
+	return result.String(), nil
+}
+
+// LookupExtended returns the value of the extended reference with the name,
+// i.e. the part between "${" and "}".
+func (in *interpolator) lookupExtended(sectionName, name string) (string, error) {
+	if strings.HasPrefix(name, envPrefix) {
+		name = name[len(envPrefix):]
+		var def string
+		i := strings.Index(name, envDefault)
+		if i != -1 {
+			name, def = name[:i], name[i+len(envDefault):]
+		}
+
+		if value, ok := in.opts.LookupEnv(name); ok && value != "" {
+			return value, nil
+		} else if i != -1 {
+			return def, nil
+		}
+		return "", in.errorf("environment variable %q isn't set", name)
+	}
+
+	if i := strings.LastIndexByte(name, '.'); i != -1 {
+		if _, ok := in.config[name[:i]][name[i+1:]]; ok {
+			return in.resolve(name[:i], name[i+1:])
+		}
+		return "", in.errorf("reference to unknown key %q", name)
+	}
+	return in.lookupKey(sectionName, name)
+}
+
+func (in *interpolator) expandBasic(sectionName, value string) (string, error) {
+	var result strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' {
+			result.WriteByte(value[i])
+			continue
+		}
+
+		if i+1 < len(value) && value[i+1] == '%' {
+			result.WriteByte('%')
+			i++
+			continue
+		} else if i+1 >= len(value) || value[i+1] != '(' {
+			return "", in.errorf("'%%' must be followed by '%%' or '(', found %q", value[i:])
+		}
+
+		end := strings.IndexByte(value[i+2:], ')')
+		if end == -1 || i+2+end+1 >= len(value) || value[i+2+end+1] != 's' {
+			return "", in.errorf("bad interpolation syntax in %q", value[i:])
+		}
+		replacement, err := in.lookupKey(sectionName, value[i+2:i+2+end])
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(replacement)
+		i += 2 + end + 1
+	}
+	return result.String(), nil
+}
+
+// LookupKey returns the value of the key in the section, or in the global
+// section if it's not defined in the section.
+func (in *interpolator) lookupKey(sectionName, key string) (string, error) {
+	if _, ok := in.config[sectionName][key]; ok {
+		return in.resolve(sectionName, key)
+	} else if _, ok := in.config[Global][key]; ok {
+		return in.resolve(Global, key)
+	}
+	return "", in.errorf("reference to unknown key %q", key)
+}
+
+// Errorf returns an error that includes the chain of keys being interpolated.
+func (in *interpolator) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("ini: error interpolating %s: %s", formatChain(in.chain),
+		fmt.Sprintf(format, args...))
+}
+
+func formatChain(chain []reference) string {
+	names := make([]string, len(chain))
+	for i, ref := range chain {
+		names[i] = ref.String()
+	}
+	return strings.Join(names, " -> ")
+}
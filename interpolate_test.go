@@ -0,0 +1,142 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testLookupEnv(name string) (string, bool) {
+	env := map[string]string{"HOME": "/home/bob", "EMPTY": ""}
+	value, ok := env[name]
+	return value, ok
+}
+
+func TestConfigInterpolate(t *testing.T) {
+	t.Parallel()
+	content := `name = app
+root = /srv/${name}
+price = $$10 or $5
+[paths]
+logs = ${root}/logs
+home = ${env:HOME}/${name}
+tmp = ${env:TMPDIR:-/tmp}
+empty = ${env:EMPTY:-default}
+[http]
+root = ${paths.logs}/http
+global = ${.root}
+[database.replica]
+host = db
+[other]
+host = ${database.replica.host}`
+	c, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	expected := Config{
+		Global: {
+			"name":  "app",
+			"root":  "/srv/app",
+			"price": "$10 or $5",
+		},
+		"paths": {
+			"logs":  "/srv/app/logs",
+			"home":  "/home/bob/app",
+			"tmp":   "/tmp",
+			"empty": "default",
+		},
+		"http": {
+			"root":   "/srv/app/logs/http",
+			"global": "/srv/app",
+		},
+		"database.replica": {"host": "db"},
+		"other":            {"host": "db"},
+	}
+
+	got, err := c.Interpolate(InterpolateOptions{LookupEnv: testLookupEnv})
+	if err != nil {
+		t.Fatalf("Unexpected error interpolating: %s", err.Error())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, but got %v", expected, got)
+	}
+	if value := c[Global]["root"]; value != "/srv/${name}" {
+		t.Fatalf("Expected the config to be unchanged, but got %q", value)
+	}
+}
+
+func TestConfigInterpolateBasic(t *testing.T) {
+	t.Parallel()
+	c := Config{
+		Global: {"base": "/srv"},
+		"paths": {
+			"home":    "%(base)s/home",
+			"logs":    "%(home)s/logs",
+			"percent": "100%%",
+			"env":     "${HOME}",
+		},
+	}
+	expected := Config{
+		Global: {"base": "/srv"},
+		"paths": {
+			"home":    "/srv/home",
+			"logs":    "/srv/home/logs",
+			"percent": "100%",
+			"env":     "${HOME}",
+		},
+	}
+
+	got, err := c.Interpolate(InterpolateOptions{Syntax: BasicInterpolation})
+	if err != nil {
+		t.Fatalf("Unexpected error interpolating: %s", err.Error())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, but got %v", expected, got)
+	}
+}
+
+func TestConfigInterpolateError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		config   Config
+		syntax   Interpolation
+		expected string
+	}{
+		{Config{Global: {"a": "${b}", "b": "${c}", "c": "${a}"}}, ExtendedInterpolation,
+			"ini: interpolation cycle: a -> b -> c -> a"},
+		{Config{"s": {"a": "${a}"}}, ExtendedInterpolation,
+			"ini: interpolation cycle: s.a -> s.a"},
+		{Config{Global: {"a": "${s.b}"}, "s": {"b": "${unknown}"}}, ExtendedInterpolation,
+			`ini: error interpolating a -> s.b: reference to unknown key "unknown"`},
+		{Config{Global: {"a": "${s.b}"}}, ExtendedInterpolation,
+			`ini: error interpolating a: reference to unknown key "s.b"`},
+		{Config{Global: {"a": "${b"}}, ExtendedInterpolation,
+			`ini: error interpolating a: unclosed reference "${b"`},
+		{Config{Global: {"a": "${env:UNKNOWN}"}}, ExtendedInterpolation,
+			`ini: error interpolating a: environment variable "UNKNOWN" isn't set`},
+		{Config{Global: {"a": "%(b)s", "b": "%(a)s"}}, BasicInterpolation,
+			"ini: interpolation cycle: a -> b -> a"},
+		{Config{Global: {"a": "100%"}}, BasicInterpolation,
+			`ini: error interpolating a: '%' must be followed by '%' or '(', found "%"`},
+		{Config{Global: {"a": "%(b)"}, "s": {"b": ""}}, BasicInterpolation,
+			`ini: error interpolating a: bad interpolation syntax in "%(b)"`},
+		{Config{Global: {"a": "%(b)s"}, "s": {"b": ""}}, BasicInterpolation,
+			`ini: error interpolating a: reference to unknown key "b"`},
+	}
+
+	for _, test := range tests {
+		opts := InterpolateOptions{Syntax: test.syntax, LookupEnv: testLookupEnv}
+		_, err := test.config.Interpolate(opts)
+		if err == nil {
+			t.Fatalf("Expected an error interpolating %v, but didn't get one", test.config)
+		} else if err.Error() != test.expected {
+			t.Fatalf("Expected error interpolating %v to be %q, but got %q",
+				test.config, test.expected, err.Error())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import "bytes"
+
+// Maximum value of an int, used as maximum line length if there is no limit.
+const maxInt = int(^uint(0) >> 1)
+
+// Limits limits the resources used in parsing, for example when parsing input
+// from untrusted sources. A zero field means no limit, which is the default.
+// If a limit is exceeded parsing stops with a LimitError, also in recovery
+// mode.
+//
+//	opts := ini.Options{Limits: ini.Limits{
+//		MaxLineLength: 4 * 1024,
+//		MaxBytes:      1024 * 1024,
+//		MaxSections:   100,
+//	}}
+type Limits struct {
+	// MaxLineLength is the maximum length of a single line in bytes, not
+	// including the line ending.
+	MaxLineLength int
+
+	// MaxValueSize is the maximum size of a value in bytes, after joining
+	// continuation lines and decoding escape sequences.
+	MaxValueSize int
+
+	// MaxSections is the maximum number of sections, not including the global
+	// section.
+	MaxSections int
+
+	// MaxKeysPerSection is the maximum number of distinct keys in a single
+	// section.
+	MaxKeysPerSection int
+
+	// MaxBytes is the maximum size of the input in bytes, including all
+	// included files.
+	MaxBytes int64
+}
+
+// The names of the limits, used in LimitError.
+const (
+	limitLineLength     = "MaxLineLength"
+	limitValueSize      = "MaxValueSize"
+	limitSections       = "MaxSections"
+	limitKeysPerSection = "MaxKeysPerSection"
+	limitBytes          = "MaxBytes"
+)
+
+// CheckValue checks the size of the value.
+func (l *Limits) checkValue(value string) error {
+	if l.MaxValueSize > 0 && len(value) > l.MaxValueSize {
+		return LimitError{Limit: limitValueSize, Max: int64(l.MaxValueSize)}
+	}
+	return nil
+}
+
+// CheckSections checks the number of sections.
+func (l *Limits) checkSections(n int) error {
+	if l.MaxSections > 0 && n > l.MaxSections {
+		return LimitError{Limit: limitSections, Max: int64(l.MaxSections)}
+	}
+	return nil
+}
+
+// CheckKeys checks the number of keys in a section.
+func (l *Limits) checkKeys(n int) error {
+	if l.MaxKeysPerSection > 0 && n > l.MaxKeysPerSection {
+		return LimitError{Limit: limitKeysPerSection, Max: int64(l.MaxKeysPerSection)}
+	}
+	return nil
+}
+
+// Split is the split function of the scanner, see scanLines. It checks the
+// length of the lines and the total number of bytes read.
+func (lr *lineReader) split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := scanLines(data, atEOF)
+	if err != nil {
+		return advance, token, err
+	}
+
+	// If there is no complete line yet we check the length of the line so
+	// far, so we don't keep reading a line that's too long.
+	line := token
+	if advance == 0 {
+		line = data
+	}
+
+	limits := &lr.opts.Limits
+	if limits.MaxLineLength > 0 && len(bytes.TrimRight(line, "\r\n")) > limits.MaxLineLength {
+		return 0, nil, LimitError{
+			Limit:    limitLineLength,
+			Max:      int64(limits.MaxLineLength),
+			Position: Position{Line: lr.lineNumber + 1},
+		}
+	}
+	if limits.MaxBytes > 0 && *lr.bytesRead+int64(len(line)) > limits.MaxBytes {
+		return 0, nil, LimitError{
+			Limit:    limitBytes,
+			Max:      limits.MaxBytes,
+			Position: Position{Line: lr.lineNumber + 1},
+		}
+	}
+
+	*lr.bytesRead += int64(advance)
+	return advance, token, nil
+}
+
+// LimitError sets the position of the error, which is found on the line with
+// the line number.
+func (p *parser) limitError(err LimitError, lineNumber int) LimitError {
+	err.Position = Position{File: p.file(), Line: lineNumber}
+	return err
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseLongLine(t *testing.T) {
+	t.Parallel()
+	value := strings.Repeat("a", 1024*1024)
+	c, err := Parse(strings.NewReader("[section]\nkey = " + value + "\nkey2 = value"))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	if got := c["section"]["key"]; got != value {
+		t.Fatalf("Expected the value to have a length of %d, but got %d",
+			len(value), len(got))
+	} else if got := c["section"]["key2"]; got != "value" {
+		t.Fatalf("Expected the value to be %q, but got %q", "value", got)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		content  string
+		limits   Limits
+		expected string
+	}{
+		{"key = value\nkey2 = too long", Limits{MaxLineLength: 11},
+			"ini: exceeded the maximum line length of 11 bytes on line 2"},
+		{"key = value\r\n" + strings.Repeat("a", 100000), Limits{MaxLineLength: 11},
+			"ini: exceeded the maximum line length of 11 bytes on line 2"},
+		{"key = value\nkey2 = \"long\\nvalue\"", Limits{MaxValueSize: 9},
+			"ini: exceeded the maximum value size of 9 bytes on line 2"},
+		{"key = first\n  second", Limits{MaxValueSize: 6},
+			"ini: exceeded the maximum value size of 6 bytes on line 2"},
+		{"key = first \\\n  second", Limits{MaxValueSize: 6},
+			"ini: exceeded the maximum value size of 6 bytes on line 1"},
+		{"[a]\n[b]\n[c]", Limits{MaxSections: 2},
+			"ini: exceeded the maximum of 2 sections on line 3"},
+		{"a = 1\nb = 2\n[section]\na = 1\nb = 2\nc = 3", Limits{MaxKeysPerSection: 2},
+			"ini: exceeded the maximum of 2 keys per section on line 6"},
+		{"key = value\nkey2 = value2\n", Limits{MaxBytes: 20},
+			"ini: exceeded the maximum input size of 20 bytes on line 2"},
+	}
+
+	for _, test := range tests {
+		opts := Options{Limits: test.limits, Recover: true,
+			BackslashContinuation: true, IndentedContinuation: true}
+		_, err := ParseWithOptions(strings.NewReader(test.content), opts)
+		var limitErr LimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("Expected a LimitError parsing %q, but got %v", test.content, err)
+		} else if err.Error() != test.expected {
+			t.Fatalf("Expected error %q, but got %q", test.expected, err.Error())
+		}
+	}
+
+	// Within the limits.
+	limits := Limits{
+		MaxLineLength:     11,
+		MaxValueSize:      5,
+		MaxSections:       1,
+		MaxKeysPerSection: 1,
+		MaxBytes:          45,
+	}
+	content := "key = value\n[section]\nkey = value\n"
+	opts := Options{Limits: limits, DuplicateKeys: KeyLastWins}
+	if _, err := ParseWithOptions(strings.NewReader(content+content[:11]), opts); err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+}
+
+func TestParseFSLimits(t *testing.T) {
+	t.Parallel()
+	opts := Options{Limits: Limits{MaxBytes: 40}}
+	_, err := ParseFS(includeFS, "error.ini", opts)
+	expected := "ini: exceeded the maximum input size of 40 bytes in invalid.ini on line 1"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, but got %v", expected, err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// LineEnding is the style of line endings used in writing ini formatted
+// output.
+type LineEnding uint8
+
+// The supported line endings.
+const (
+	// AutoLineEnding uses the line endings of the input, see
+	// Document.LineEnding, or LF if there is no input. As a Config doesn't
+	// hold the line ending of its input, use Metadata.LineEnding to write it
+	// with the same line endings.
+	AutoLineEnding LineEnding = iota
+	// LF ends lines with a line feed, "\n", as is common on Unix-like systems.
+	LF
+	// CRLF ends lines with a carriage return and a line feed, "\r\n", as is
+	// common on Windows.
+	CRLF
+)
+
+// String returns the name of the line ending.
+func (le LineEnding) String() string {
+	switch le {
+	case AutoLineEnding:
+		return "auto"
+	case LF:
+		return "LF"
+	case CRLF:
+		return "CRLF"
+	default:
+		return "LineEnding(" + strconv.Itoa(int(le)) + ")"
+	}
+}
+
+// Bytes returns the characters of the line ending, AutoLineEnding is treated
+// as LF.
+func (le LineEnding) bytes() []byte {
+	if le == CRLF {
+		return []byte("\r\n")
+	}
+	return []byte("\n")
+}
+
+// LineEnding returns the dominant line ending of the document, i.e. the line
+// ending used by most lines, or LF if no line uses CRLF. New lines, added by
+// Set and Add, use this line ending.
+//
+// Unchanged lines keep their own line ending, so a document with mixed line
+// endings is written back as is, see SetLineEnding to change that.
+func (d *Document) LineEnding() LineEnding {
+	var lf, crlf int
+	for _, n := range d.nodes {
+		if n.included {
+			continue
+		}
+		for raw := n.raw; ; {
+			i := bytes.IndexByte(raw, '\n')
+			if i == -1 {
+				break
+			}
+			if i > 0 && raw[i-1] == '\r' {
+				crlf++
+			} else {
+				lf++
+			}
+			raw = raw[i+1:]
+		}
+	}
+	if crlf > lf {
+		return CRLF
+	}
+	return LF
+}
+
+// SetLineEnding changes the line endings of all lines in the document to le.
+// AutoLineEnding changes all lines to the dominant line ending, see
+// LineEnding.
+func (d *Document) SetLineEnding(le LineEnding) {
+	if le == AutoLineEnding {
+		le = d.LineEnding()
+	}
+	for _, n := range d.nodes {
+		n.raw = convertLineEndings(n.raw, le)
+	}
+}
+
+// ConvertLineEndings returns the input with all line endings changed to le.
+func convertLineEndings(input []byte, le LineEnding) []byte {
+	ending := le.bytes()
+	output := make([]byte, 0, len(input))
+	for {
+		i := bytes.IndexByte(input, '\n')
+		if i == -1 {
+			return append(output, input...)
+		}
+		output = append(output, bytes.TrimSuffix(input[:i], []byte("\r"))...)
+		output = append(output, ending...)
+		input = input[i+1:]
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocumentLineEnding(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		expected LineEnding
+	}{
+		{"", LF},
+		{"key = value", LF},
+		{"key = value\n", LF},
+		{"key = value\r\n", CRLF},
+		{"; comment\r\n[section]\r\nkey = value\n", CRLF},
+		{"; comment\r\n[section]\nkey = value\n", LF},
+		{"key = first \\\r\n\tsecond\r\nkey2 = value\n", CRLF},
+	}
+
+	opts := Options{BackslashContinuation: true}
+	for _, test := range tests {
+		doc, err := ParseDocumentWithOptions(strings.NewReader(test.input), opts)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %s", test.input, err.Error())
+		} else if got := doc.LineEnding(); got != test.expected {
+			t.Fatalf("Expected the line ending of %q to be %s, but got %s",
+				test.input, test.expected, got)
+		} else if got := doc.String(); got != test.input {
+			t.Fatalf("Expected Document.String() to return %q, but got %q",
+				test.input, got)
+		}
+	}
+}
+
+func TestDocumentLineEndingChanges(t *testing.T) {
+	t.Parallel()
+	input := "key = value\r\n[section]\nkey = value\r\n"
+	doc, err := ParseDocument(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	setValue(t, doc, Global, "key", "changed")
+	setValue(t, doc, Global, "new", "value")
+	setValue(t, doc, "section", "new", "value")
+	setValue(t, doc, "new", "key", "value")
+	expected := "key = changed\r\nnew=value\r\n[section]\nkey = value\r\n" +
+		"new=value\r\n\r\n[new]\r\nkey=value\r\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected the document to be %q, but got %q", expected, got)
+	}
+
+	doc.SetLineEnding(LF)
+	expected = strings.Replace(expected, "\r\n", "\n", -1)
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected the document to be %q, but got %q", expected, got)
+	}
+
+	doc, err = ParseDocument(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+	doc.SetLineEnding(AutoLineEnding)
+	expected = "key = value\r\n[section]\r\nkey = value\r\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected the document to be %q, but got %q", expected, got)
+	}
+
+	// A last line without a line ending gets one before a new line is added.
+	doc, err = ParseDocument(strings.NewReader("a = 1\r\nb = 2"))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+	setValue(t, doc, Global, "c", "3")
+	expected = "a = 1\r\nb = 2\r\nc=3\r\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected the document to be %q, but got %q", expected, got)
+	}
+}
+
+func TestEncoderLineEnding(t *testing.T) {
+	t.Parallel()
+	c := Config{Global: {"key": "value"}, "section": {"key": "value"}}
+	doc, err := ParseDocument(strings.NewReader("a = 1\r\nb = 2\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	tests := []struct {
+		v          interface{}
+		lineEnding LineEnding
+		expected   string
+	}{
+		{c, AutoLineEnding, "\"key\"=\"value\"\n\n[section]\n\"key\"=\"value\"\n\n"},
+		{c, LF, "\"key\"=\"value\"\n\n[section]\n\"key\"=\"value\"\n\n"},
+		{c, CRLF, "\"key\"=\"value\"\r\n\r\n[section]\r\n\"key\"=\"value\"\r\n\r\n"},
+		{doc, AutoLineEnding, "a = 1\r\nb = 2\n"},
+		{doc, LF, "a = 1\nb = 2\n"},
+		{doc, CRLF, "a = 1\r\nb = 2\r\n"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.LineEnding = test.lineEnding
+		if err := enc.Encode(test.v); err != nil {
+			t.Fatalf("Unexpected error encoding: %s", err.Error())
+		} else if got := buf.String(); got != test.expected {
+			t.Fatalf("Expected encoding with %s line endings to write %q, but got %q",
+				test.lineEnding, test.expected, got)
+		}
+	}
+}
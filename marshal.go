@@ -0,0 +1,196 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag value that skips a field in encoding and decoding.
+const skipTag = "-"
+
+// Marshal returns the configuration of a struct, the inverse of
+// Config.Decode. Decoding the returned configuration results in the same
+// struct.
+//
+//	c, err := ini.Marshal(struct {
+//		Name     string
+//		Database struct {
+//			Host string `ini:"hostname"`
+//			Port int
+//		}
+//	}{...})
+//
+// Fields are encoded as keys named after the field, or the name in the "ini"
+// tag, in the global section. Struct fields are encoded as sections and struct
+// fields inside those as subsections, e.g. "[Database.Replica]". Fields with
+// the tag `ini:"-"` and unexported fields are skipped.
+//
+// Strings, booleans, numbers, time.Duration, time.Time and slices of those
+// are supported. Slices are encoded as comma separated lists, so strings in a
+// slice can't contain commas or start or end with whitespace, and nil or empty
+// slices are skipped, decoding into a nil slice. Times are encoded in the
+// RFC 3339 format, so the location of a time isn't kept, only its offset.
+//
+// An error is returned for a field of any other type, or a value that can't be
+// encoded.
+func Marshal(src interface{}) (Config, error) {
+	c, _, err := marshal(src)
+	return c, err
+}
+
+// Marshal returns the configuration of the struct and the order of its
+// fields, see Marshal.
+func marshal(src interface{}) (Config, *order, error) {
+	value := reflect.ValueOf(src)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("ini: can't encode type %T, expected a struct or a pointer to a struct", src)
+	} else if value.Type() == reflect.TypeOf(Document{}) {
+		// A Document has no exported fields, so it would silently be empty.
+		return nil, nil, fmt.Errorf("ini: can't marshal type %T, use Document.Config", src)
+	}
+
+	c := Config{Global: {}}
+	o := &order{sections: []string{Global}, keys: map[string][]string{}}
+	if err := marshalStruct(c, o, value, nil, ""); err != nil {
+		return nil, nil, err
+	}
+	return c, o, nil
+}
+
+// Encode writes the configuration of a struct to the writer in the ini format,
+// see Marshal and Encoder.
+func Encode(w io.Writer, src interface{}) error {
+	return NewEncoder(w).Encode(src)
+}
+
+// MarshalStruct adds the fields of the struct to the section with the path,
+// and the order of the fields to o. FieldPath is the path to the struct, used
+// in errors.
+func marshalStruct(c Config, o *order, value reflect.Value, path []string, fieldPath string) error {
+	sectionName := sectionPathName(path)
+	if _, ok := c[sectionName]; !ok {
+		c[sectionName] = Section{}
+		if sectionName != Global {
+			o.sections = append(o.sections, sectionName)
+		}
+	}
+
+	valueType := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		structField := valueType.Field(i)
+		tag := structField.Tag.Get("ini")
+		if structField.PkgPath != "" || tag == skipTag {
+			continue
+		}
+
+		field := value.Field(i)
+		fieldName := structField.Name
+		if fieldPath != "" {
+			fieldName = fieldPath + "." + fieldName
+		}
+
+		if isSection(field) {
+			subPath := []string{structField.Name}
+			if tag != "" {
+				subPath = SplitSection(tag)
+			}
+			subPath = append(path[:len(path):len(path)], subPath...)
+			if err := marshalStruct(c, o, field, subPath, fieldName); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := structField.Name
+		if tag != "" {
+			key = tag
+		}
+		if _, ok := c[sectionName][key]; ok {
+			return fmt.Errorf("ini: can't encode field %s: key %q is already used",
+				fieldName, key)
+		}
+
+		if field.Kind() == reflect.Slice && field.Len() == 0 {
+			continue
+		}
+		formatted, err := marshalValue(field)
+		if err != nil {
+			return fmt.Errorf("ini: can't encode field %s: %s", fieldName, err.Error())
+		}
+		c[sectionName][key] = formatted
+		o.keys[sectionName] = append(o.keys[sectionName], key)
+	}
+	return nil
+}
+
+// MarshalValue formats a single value so that it's decoded into the same
+// value, see setReflectValue.
+func marshalValue(value reflect.Value) (string, error) {
+	switch value.Type() {
+	case typeDuration:
+		return time.Duration(value.Int()).String(), nil
+	case typeTime:
+		return value.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, value.Type().Bits()), nil
+	case reflect.Slice:
+		return marshalSlice(value)
+	default:
+		return "", fmt.Errorf("unsupported type %s", value.Type())
+	}
+}
+
+// MarshalSlice formats a slice as a comma separated list, see getValues.
+func marshalSlice(value reflect.Value) (string, error) {
+	if elem := value.Type().Elem(); elem.Kind() == reflect.Slice {
+		return "", fmt.Errorf("unsupported type %s", value.Type())
+	}
+
+	values := make([]string, value.Len())
+	for i := range values {
+		formatted, err := marshalValue(value.Index(i))
+		if err != nil {
+			return "", err
+		} else if strings.Contains(formatted, ",") || strings.TrimSpace(formatted) != formatted {
+			return "", fmt.Errorf("%q can't be part of a comma separated list", formatted)
+		}
+		values[i] = formatted
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// SectionPathName returns the name of the section with the path, which
+// SplitSection splits into the same path. Elements are quoted if needed.
+func sectionPathName(path []string) string {
+	elements := make([]string, len(path))
+	for i, element := range path {
+		if element == "" || strings.ContainsAny(element, `."\`) ||
+			strings.TrimSpace(element) != element {
+			element = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(element) + `"`
+		}
+		elements[i] = element
+	}
+	return strings.Join(elements, ".")
+}
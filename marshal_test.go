@@ -0,0 +1,231 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type marshalTestData struct {
+	Name     string
+	Message  string `ini:"msg"`
+	Debug    bool
+	Workers  int8
+	Max      uint64
+	Ratio    float32
+	Timeout  time.Duration
+	Started  time.Time
+	Tags     []string
+	Ports    []int
+	Weights  []float64
+	Delays   []time.Duration
+	Flags    []bool
+	Empty    []string
+	Skipped  string `ini:"-"`
+	private  string
+	Database struct {
+		Host    string
+		Replica struct {
+			Host string
+		}
+		Backup struct {
+			Host string
+		} `ini:"backup \"my.host\""`
+	}
+	HTTP struct {
+		Port  uint16
+		Dates []time.Time
+	} `ini:"http"`
+}
+
+func newMarshalTestData() marshalTestData {
+	var data marshalTestData
+	data.Name = "app"
+	data.Message = " Welcome,\n\"Bob\" "
+	data.Debug = true
+	data.Workers = -8
+	data.Max = math.MaxUint64
+	data.Ratio = 0.1
+	data.Timeout = 90 * time.Second
+	data.Started = time.Date(2016, 4, 2, 12, 31, 57, 123, time.UTC)
+	data.Tags = []string{"a", "b c", ""}
+	data.Ports = []int{80, 443}
+	data.Weights = []float64{1.5, -2e10}
+	data.Delays = []time.Duration{time.Millisecond, time.Hour}
+	data.Flags = []bool{true, false}
+	data.Database.Host = "db"
+	data.Database.Replica.Host = "replica"
+	data.Database.Backup.Host = "backup"
+	data.HTTP.Port = 8080
+	data.HTTP.Dates = []time.Time{time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC)}
+	return data
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+	data := newMarshalTestData()
+	got, err := Marshal(&data)
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling: %s", err.Error())
+	}
+
+	expected := Config{
+		Global: {
+			"Name":    "app",
+			"msg":     " Welcome,\n\"Bob\" ",
+			"Debug":   "true",
+			"Workers": "-8",
+			"Max":     "18446744073709551615",
+			"Ratio":   "0.1",
+			"Timeout": "1m30s",
+			"Started": "2016-04-02T12:31:57.000000123Z",
+			"Tags":    "a, b c, ",
+			"Ports":   "80, 443",
+			"Weights": "1.5, -2e+10",
+			"Delays":  "1ms, 1h0m0s",
+			"Flags":   "true, false",
+		},
+		"Database":                  {"Host": "db"},
+		"Database.Replica":          {"Host": "replica"},
+		`Database.backup."my.host"`: {"Host": "backup"},
+		"http":                      {"Port": "8080", "Dates": "2016-01-02T00:00:00Z"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Marshal to return %v, but got %v", expected, got)
+	}
+
+	if got2, err := Marshal(data); err != nil || !reflect.DeepEqual(got2, got) {
+		t.Fatalf("Expected marshaling a struct and a pointer to be the same, "+
+			"but got %v and %v (%v)", got, got2, err)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	t.Parallel()
+	data := newMarshalTestData()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, data); err != nil {
+		t.Fatalf("Unexpected error encoding: %s", err.Error())
+	}
+
+	var got marshalTestData
+	if err := Decode(&buf, &got); err != nil {
+		t.Fatalf("Unexpected error decoding: %s", err.Error())
+	} else if !reflect.DeepEqual(got, data) {
+		t.Fatalf("Expected to decode %+v, but got %+v", data, got)
+	}
+
+	// Zero values are written as well.
+	var zero, gotZero marshalTestData
+	buf.Reset()
+	if err := Encode(&buf, zero); err != nil {
+		t.Fatalf("Unexpected error encoding: %s", err.Error())
+	} else if err := Decode(&buf, &gotZero); err != nil {
+		t.Fatalf("Unexpected error decoding: %s", err.Error())
+	} else if !reflect.DeepEqual(gotZero, zero) {
+		t.Fatalf("Expected to decode %+v, but got %+v", zero, gotZero)
+	}
+}
+
+func TestMarshalSkip(t *testing.T) {
+	t.Parallel()
+	data := struct {
+		Key     string
+		Skipped string `ini:"-"`
+	}{"value", "skipped"}
+	c, err := Marshal(data)
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling: %s", err.Error())
+	} else if expected := (Config{Global: {"Key": "value"}}); !reflect.DeepEqual(c, expected) {
+		t.Fatalf("Expected %v, but got %v", expected, c)
+	}
+
+	c[Global]["-"] = "decoded"
+	c[Global]["Skipped"] = "decoded"
+	data.Skipped = ""
+	if err := c.Decode(&data); err != nil {
+		t.Fatalf("Unexpected error decoding: %s", err.Error())
+	} else if data.Skipped != "" {
+		t.Fatalf("Expected the skipped field not to be decoded, but got %q", data.Skipped)
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		src      interface{}
+		expected string
+	}{
+		{"string", "ini: can't encode type string, expected a struct or a pointer to a struct"},
+		{struct{ M map[string]string }{}, "ini: can't encode field M: unsupported type map[string]string"},
+		{struct{ P *int }{}, "ini: can't encode field P: unsupported type *int"},
+		{struct{ S [][]string }{[][]string{{"a"}}},
+			"ini: can't encode field S: unsupported type [][]string"},
+		{struct{ S []struct{} }{[]struct{}{{}}},
+			"ini: can't encode field S: unsupported type struct {}"},
+		{struct{ S []string }{[]string{"a,b"}},
+			`ini: can't encode field S: "a,b" can't be part of a comma separated list`},
+		{struct{ S []string }{[]string{" a"}},
+			`ini: can't encode field S: " a" can't be part of a comma separated list`},
+		{struct {
+			A string `ini:"key"`
+			B string `ini:"key"`
+		}{}, `ini: can't encode field B: key "key" is already used`},
+		{struct {
+			Section struct {
+				C complex64
+			}
+		}{}, "ini: can't encode field Section.C: unsupported type complex64"},
+	}
+
+	for _, test := range tests {
+		_, err := Marshal(test.src)
+		if err == nil {
+			t.Fatalf("Expected Marshal(%#v) to return an error, but got none", test.src)
+		} else if err.Error() != test.expected {
+			t.Fatalf("Expected Marshal(%#v) to return error %q, but got %q",
+				test.src, test.expected, err.Error())
+		}
+
+		if err := Encode(&bytes.Buffer{}, test.src); err == nil || err.Error() != test.expected {
+			t.Fatalf("Expected Encode(%#v) to return error %q, but got %v",
+				test.src, test.expected, err)
+		}
+	}
+
+	// Encode accepts a Document, see TestEncoderDocument, Marshal doesn't.
+	for _, src := range []interface{}{Document{}, &Document{}} {
+		expected := fmt.Sprintf("ini: can't marshal type %T, use Document.Config", src)
+		if _, err := Marshal(src); err == nil || err.Error() != expected {
+			t.Fatalf("Expected Marshal(%T) to return error %q, but got %v", src, expected, err)
+		}
+	}
+}
+
+func TestEncodeStruct(t *testing.T) {
+	t.Parallel()
+	data := struct {
+		Name     string
+		Database struct {
+			Port int
+		}
+	}{Name: "app"}
+	data.Database.Port = 5432
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, data); err != nil {
+		t.Fatalf("Unexpected error encoding: %s", err.Error())
+	}
+	expected := "\"Name\"=\"app\"\n\n[Database]\n\"Port\"=\"5432\"\n\n"
+	if got := buf.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import "io"
+
+// Metadata holds what is known about parsed input that a Config, being a plain
+// map, can't hold, such as all values of repeated keys, see ParseWithMetadata.
+// It describes the input as parsed, later changes to the Config aren't
+// reflected.
+type Metadata struct {
+	doc *Document
+}
+
+// ParseWithMetadata parses ini formatted input, using the options to
+// determine the syntax, like ParseWithOptions. Along with the configuration it
+// returns the Metadata of the input.
+//
+//	config, meta, err := ini.ParseWithMetadata(r, ini.SystemdDialect.Options())
+//	if err != nil {
+//		// Handle error.
+//	}
+//	environment := meta.Values("Service", "Environment")
+//
+// In recovery mode, see Options.Recover, the metadata of the lines without
+// syntax errors is returned along with the ErrorList.
+func ParseWithMetadata(r io.Reader, opts Options) (Config, *Metadata, error) {
+	p, err := newParser(r, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.doc = &Document{opts: p.opts, encoding: p.encoding}
+	if err := p.parse(); err != nil {
+		if errs, ok := err.(ErrorList); ok {
+			return p.Config, &Metadata{doc: p.doc}, errs
+		}
+		return nil, nil, err
+	}
+
+	return p.Config, &Metadata{doc: p.doc}, nil
+}
+
+// Values returns all values of the key in the section, in order. Only with the
+// KeyCollectAll policy can a key have multiple values, with any other policy
+// it returns the value in the Config. See Document.Values.
+func (m *Metadata) Values(section, key string) []string {
+	return m.doc.Values(section, key)
+}
+
+// Position returns the position in the source of the key in the section, as
+// found in the Config. It returns false if the key doesn't exist. See
+// Document.Position.
+//
+//	if pos, ok := meta.Position("http", "port"); ok {
+//		log.Printf("%s: invalid port", pos)
+//	}
+func (m *Metadata) Position(section, key string) (Position, bool) {
+	return m.doc.Position(section, key)
+}
+
+// SectionPosition returns the position in the source of the first header of
+// the section. It returns false if the section has no header, which is always
+// the case for the global section.
+func (m *Metadata) SectionPosition(section string) (Position, bool) {
+	return m.doc.SectionPosition(section)
+}
+
+// Encoding returns the character encoding of the input, as detected or set in
+// the options. Use it as the encoding of an Encoder to write the configuration
+// in the same encoding:
+//
+//	enc := ini.NewEncoder(w)
+//	enc.Encoding = meta.Encoding()
+//	err := enc.Encode(config)
+func (m *Metadata) Encoding() Encoding {
+	return m.doc.Encoding()
+}
+
+// LineEnding returns the dominant line ending of the input, i.e. the line
+// ending used by most lines, or LF if no line uses CRLF, see
+// Document.LineEnding. Like the encoding it can be set on an Encoder to write
+// the configuration with the same line endings.
+func (m *Metadata) LineEnding() LineEnding {
+	return m.doc.LineEnding()
+}
+
+// Decode decodes the configuration, as parsed, into a struct, see
+// Config.Decode. Unlike Config.Decode it decodes all values of multi-valued
+// keys, see Values, into slices, and a DecodeError includes the position of
+// the invalid value.
+func (m *Metadata) Decode(dst interface{}) error {
+	return decode(m.doc, dst, "Metadata.Decode", m.doc.options().ExtendedBools)
+}
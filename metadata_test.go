@@ -0,0 +1,246 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWithMetadata(t *testing.T) {
+	t.Parallel()
+	content := "[Service]\nEnvironment=A=1\nExecStart=/bin/app\nEnvironment=B=2\n"
+	opts := Options{DuplicateKeys: KeyCollectAll}
+	c, meta, err := ParseWithMetadata(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	expectedConfig := Config{
+		Global:    {},
+		"Service": {"Environment": "B=2", "ExecStart": "/bin/app"},
+	}
+	if !reflect.DeepEqual(c, expectedConfig) {
+		t.Fatalf("Expected the config to be %v, but got %v", expectedConfig, c)
+	}
+
+	expected := []string{"A=1", "B=2"}
+	if got := meta.Values("Service", "Environment"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Metadata.Values to return %q, but got %q", expected, got)
+	}
+	expected = []string{"/bin/app"}
+	if got := meta.Values("Service", "ExecStart"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Metadata.Values to return %q, but got %q", expected, got)
+	}
+	if got := meta.Values("Service", "Unknown"); got != nil {
+		t.Fatalf("Expected Metadata.Values to return nil, but got %q", got)
+	}
+}
+
+func TestParseWithMetadataError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		content  string
+		opts     Options
+		expected string
+	}{
+		{"key", Options{}, "ini: syntax error on line 1: no separator found"},
+		{"key=value", Options{Separators: " "}, `ini: invalid separator or comment character ' '`},
+	}
+
+	for _, test := range tests {
+		c, meta, err := ParseWithMetadata(strings.NewReader(test.content), test.opts)
+		if err == nil || err.Error() != test.expected {
+			t.Fatalf("Expected the error %q, but got %v", test.expected, err)
+		} else if c != nil || meta != nil {
+			t.Fatalf("Expected no config and metadata, but got %v and %v", c, meta)
+		}
+	}
+
+	// In recovery mode the metadata of the valid lines is returned.
+	content := "server=a\n=b\nserver=c\n"
+	opts := Options{DuplicateKeys: KeyCollectAll, Recover: true}
+	_, meta, err := ParseWithMetadata(strings.NewReader(content), opts)
+	if _, ok := err.(ErrorList); !ok {
+		t.Fatalf("Expected an ErrorList, but got %v", err)
+	}
+	expected := []string{"a", "c"}
+	if got := meta.Values(Global, "server"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected Metadata.Values to return %q, but got %q", expected, got)
+	}
+}
+
+func TestMetadataDecode(t *testing.T) {
+	t.Parallel()
+	content := "server=a\nserver=b, c\nport=80\nport=443\n[section]\nkey=/bin/a x,y\nkey=/bin/b"
+	opts := Options{DuplicateKeys: KeyCollectAll}
+	c, meta, err := ParseWithMetadata(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	var got multiValueTestData
+	if err := meta.Decode(&got); err != nil {
+		t.Fatalf("Unexpected error decoding: %s", err.Error())
+	}
+	var expected multiValueTestData
+	expected.Server = "b, c"
+	expected.Servers = []string{"a", "b, c"}
+	expected.Ports = []int{80, 443}
+	expected.Section.Key = []string{"/bin/a x,y", "/bin/b"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, but got %v", expected, got)
+	}
+
+	// The Config only holds the last value.
+	got = multiValueTestData{}
+	if err := c.Decode(&got); err != nil {
+		t.Fatalf("Unexpected error decoding config: %s", err.Error())
+	} else if expected := []string{"/bin/b"}; !reflect.DeepEqual(got.Section.Key, expected) {
+		t.Fatalf("Expected Config.Decode to decode %q, but got %q", expected, got.Section.Key)
+	}
+
+	if err := meta.Decode(got); err == nil {
+		t.Fatal("Expected an error decoding into a non-pointer")
+	} else if expected := "ini: Metadata.Decode requires a pointer to a struct"; err.Error() != expected {
+		t.Fatalf("Expected error %q, but got %q", expected, err.Error())
+	}
+}
+
+func TestMetadataEncoding(t *testing.T) {
+	t.Parallel()
+	// The output of Config.WriteTo, so it can be compared byte for byte.
+	content := "\"key\"=\"välue\"\n\n"
+	tests := []struct {
+		input []byte
+		opts  Options
+		enc   Encoding
+	}{
+		{[]byte(content), Options{}, UTF8},
+		{append([]byte("\xEF\xBB\xBF"), content...), Options{}, UTF8BOM},
+		{encodeUTF16(content, true), Options{}, UTF16LE},
+		{encodeUTF16(content, false), Options{}, UTF16BE},
+		{[]byte("\"key\"=\"v\xE4lue\"\n\n"), Options{Encoding: Latin1}, Latin1},
+	}
+
+	for _, test := range tests {
+		c, meta, err := ParseWithMetadata(bytes.NewReader(test.input), test.opts)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing: %s", err.Error())
+		} else if got := meta.Encoding(); got != test.enc {
+			t.Fatalf("Expected the encoding to be %s, but got %s", test.enc, got)
+		}
+
+		// Written back in the same encoding.
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.Encoding = meta.Encoding()
+		if err := enc.Encode(c); err != nil {
+			t.Fatalf("Unexpected error encoding: %s", err.Error())
+		} else if got := buf.Bytes(); !bytes.Equal(got, test.input) {
+			t.Fatalf("Expected %q, but got %q", test.input, got)
+		}
+	}
+}
+
+func TestMetadataLineEnding(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		content  string
+		expected LineEnding
+	}{
+		{"", LF},
+		{"key=value", LF},
+		{"\"key\"=\"value\"\n\n", LF},
+		{"\"key\"=\"value\"\r\n\r\n", CRLF},
+		{"a=1\r\nb=2\nc=3\r\n", CRLF},
+		{"a=1\r\nb=2\nc=3\n", LF},
+	}
+
+	for _, test := range tests {
+		c, meta, err := ParseWithMetadata(strings.NewReader(test.content), Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error parsing: %s", err.Error())
+		} else if got := meta.LineEnding(); got != test.expected {
+			t.Fatalf("Expected the line ending of %q to be %s, but got %s",
+				test.content, test.expected, got)
+		}
+
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.LineEnding = meta.LineEnding()
+		if err := enc.Encode(c); err != nil {
+			t.Fatalf("Unexpected error encoding: %s", err.Error())
+		}
+		got := buf.String()
+		if test.expected == LF && strings.Contains(got, "\r\n") {
+			t.Fatalf("Expected only LF line endings, but got %q", got)
+		} else if test.expected == CRLF && strings.Count(got, "\n") != strings.Count(got, "\r\n") {
+			t.Fatalf("Expected only CRLF line endings, but got %q", got)
+		}
+	}
+
+	// Written back byte for byte.
+	content := "\"key\"=\"value\"\r\n\r\n"
+	c, meta, err := ParseWithMetadata(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.LineEnding = meta.LineEnding()
+	if err := enc.Encode(c); err != nil {
+		t.Fatalf("Unexpected error encoding: %s", err.Error())
+	} else if got := buf.String(); got != content {
+		t.Fatalf("Expected %q, but got %q", content, got)
+	}
+}
+
+func TestMetadataPosition(t *testing.T) {
+	t.Parallel()
+	content := "key = value\n[database]\n  user = bob\n  port = x\n"
+	_, meta, err := ParseWithMetadata(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	keyTests := []struct {
+		section, key string
+		expected     Position
+		found        bool
+	}{
+		{Global, "key", Position{Line: 1, Column: 1}, true},
+		{"database", "user", Position{Line: 3, Column: 3}, true},
+		{"database", "unknown", Position{}, false},
+	}
+	for _, test := range keyTests {
+		pos, found := meta.Position(test.section, test.key)
+		if pos != test.expected || found != test.found {
+			t.Fatalf("Expected Metadata.Position(%q, %q) to return %v, %t, but got %v, %t",
+				test.section, test.key, test.expected, test.found, pos, found)
+		}
+	}
+	expected := Position{Line: 2, Column: 1}
+	if pos, found := meta.SectionPosition("database"); pos != expected || !found {
+		t.Fatalf("Expected Metadata.SectionPosition to return %v, but got %v, %t",
+			expected, pos, found)
+	}
+
+	var got struct {
+		Database struct {
+			Port int
+		}
+	}
+	var decErr DecodeError
+	expected = Position{Line: 4, Column: 3}
+	if err := meta.Decode(&got); !errors.As(err, &decErr) {
+		t.Fatalf("Expected a DecodeError, but got %v", err)
+	} else if decErr.Position != expected {
+		t.Fatalf("Expected the error to be at %v, but got %v", expected, decErr.Position)
+	}
+}
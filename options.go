@@ -0,0 +1,325 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default separator and comment characters, see Options.
+const (
+	defaultSeparators   = "="
+	defaultCommentChars = ";#"
+
+	defaultMaxIncludeDepth = 10
+)
+
+// Options changes the way ini formatted input is parsed, see ParseWithOptions.
+// The zero value is the format that Parse accepts.
+//
+//	opts := ini.Options{
+//		Separators:         "=:",
+//		InlineCommentSpace: true,
+//	}
+//	config, err := ini.ParseWithOptions(r, opts)
+type Options struct {
+	// Separators holds the characters that separate a key from its value, the
+	// first one found is used. Defaults to "=". The first character is also
+	// used when writing new keys in a Document.
+	Separators string
+
+	// CommentChars holds the characters that start a comment. Defaults to
+	// ";#".
+	CommentChars string
+
+	// NoInlineComments disables comments after a key-value pair or section
+	// header, making the comment characters part of the value.
+	NoInlineComments bool
+
+	// InlineCommentSpace only allows inline comments that are preceded by
+	// whitespace, so that "url = http://example.com/#fragment" and
+	// "password = se;cret" keep their complete value.
+	InlineCommentSpace bool
+
+	// NoQuotes disables quoting of keys and values, making quotes and
+	// backslashes part of the key or value. Values are written as is, so they
+	// can't start or end with whitespace or contain new lines.
+	NoQuotes bool
+
+	// BackslashContinuation joins a line that ends with a backslash with the
+	// next line, dropping the backslash, the line ending and the indentation
+	// of the next line:
+	//
+	//	key = first \
+	//		second
+	//
+	// Results in "first second". By default a backslash at the end of a line
+	// is part of the value, e.g. `path = C:\dir\`.
+	BackslashContinuation bool
+
+	// IndentedContinuation adds a line indented further than the line of the
+	// key to the value, separated by a new line:
+	//
+	//	key = first
+	//		second
+	//
+	// Results in "first\nsecond". Comments and section headers, e.g.
+	// "  [section]", are never part of a value. By default indentation has no
+	// meaning.
+	IndentedContinuation bool
+
+	// AllowNoValue allows a key without a separator and value, e.g. "debug",
+	// which gets the value NoValue.
+	AllowNoValue bool
+
+	// NoValue is the value of a key without a value, see AllowNoValue.
+	// Defaults to an empty value, git-config uses "true".
+	NoValue string
+
+	// ExtendedBools makes Document.Decode and Metadata.Decode accept "yes",
+	// "on", "no" and "off", in any case, as booleans, along with the values
+	// accepted by `strconv.ParseBool`. Config.Decode, Decode and DecodeValue
+	// only accept the latter.
+	ExtendedBools bool
+
+	// SpaceAroundSeparator writes new key-value pairs in a Document as
+	// "key = value", rather than "key=value".
+	SpaceAroundSeparator bool
+
+	// CaseInsensitiveSections and CaseInsensitiveKeys make section names and
+	// keys case-insensitive, e.g. "[Database]" and "[database]" are the same
+	// section. The spelling of the first occurrence is used in Config and
+	// Document.Nodes, but Document looks up sections and keys in any case.
+	// Subsections in quotes, e.g. `[remote "Origin"]`, stay case-sensitive,
+	// like in git-config.
+	CaseInsensitiveSections bool
+	CaseInsensitiveKeys     bool
+
+	// DuplicateSections determines what happens if a section header is used
+	// more than once, defaults to returning an error.
+	DuplicateSections SectionPolicy
+
+	// DuplicateKeys determines what happens if a key is used more than once in
+	// a section, defaults to returning an error.
+	DuplicateKeys KeyPolicy
+
+	// DefaultSection is the name of a section, e.g. "DEFAULT", of which all
+	// other sections, except for the global section, inherit the keys. Keys in
+	// a section take precedence over inherited keys. Empty, the default,
+	// disables it.
+	DefaultSection string
+
+	// InheritSections allows a section to extend a parent section, inheriting
+	// all keys of the parent it doesn't define itself, by naming the parent in
+	// the header after a colon:
+	//
+	//	[base]
+	//	host = localhost
+	//	port = 8080
+	//
+	//	[prod : base]
+	//	host = example.com
+	//
+	// The section is named "prod" and has the port of the base section. A
+	// parent may extend another section, but a section can't (indirectly)
+	// extend itself. Keys in the default section are inherited after the keys
+	// of all parents.
+	InheritSections bool
+
+	// Recover enables the recovery mode, in which lines with a syntax error are
+	// skipped, rather than stopping at the first error. If any errors are found
+	// an ErrorList is returned with all errors, along with the configuration
+	// parsed from the other lines.
+	//
+	//	config, err := ini.ParseWithOptions(r, ini.Options{Recover: true})
+	//	if errs, ok := err.(ini.ErrorList); ok {
+	//		for _, err := range errs {
+	//			log.Print(err)
+	//		}
+	//	}
+	//
+	// Errors reading the input are still returned as is.
+	Recover bool
+
+	// Encoding is the character encoding of the input. A byte order mark at
+	// the start of the input takes precedence, so UTF-8 with a byte order
+	// mark and UTF-16 are always detected. Defaults to UTF-8, set it to
+	// Latin1 or Windows1252 for input in those encodings.
+	Encoding Encoding
+
+	// Limits limits the resources used in parsing, defaults to no limits.
+	Limits Limits
+
+	// MaxIncludeDepth is the maximum number of nested includes, see ParseFS.
+	// Defaults to 10.
+	MaxIncludeDepth int
+}
+
+// SectionPolicy is the policy for duplicate sections, see
+// Options.DuplicateSections.
+type SectionPolicy uint8
+
+// The policies for duplicate sections.
+const (
+	// SectionError returns an error for a duplicate section.
+	SectionError SectionPolicy = iota
+	// SectionMerge merges the key-value pairs of duplicate sections into a
+	// single section, as if they were written in one section.
+	SectionMerge
+)
+
+// KeyPolicy is the policy for duplicate keys, see Options.DuplicateKeys.
+type KeyPolicy uint8
+
+// The policies for duplicate keys.
+const (
+	// KeyError returns an error for a duplicate key.
+	KeyError KeyPolicy = iota
+	// KeyLastWins uses the value of the last key, overriding the values of the
+	// earlier keys.
+	KeyLastWins
+	// KeyFirstWins uses the value of the first key, ignoring the values of the
+	// later keys.
+	KeyFirstWins
+	// KeyCollectAll keeps all values of the key, in order, making it a
+	// multi-valued key. Config only holds the last value, the Metadata
+	// returned by ParseWithMetadata and a Document hold all of them, see
+	// Metadata.Values and Document.Values.
+	KeyCollectAll
+)
+
+// ParseWithOptions parses ini formatted input, using the options to determine
+// the syntax. See Parse for more.
+func ParseWithOptions(r io.Reader, opts Options) (Config, error) {
+	p, err := newParser(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.parse(); err != nil {
+		if errs, ok := err.(ErrorList); ok {
+			return p.Config, errs
+		}
+		return nil, err
+	}
+
+	return p.Config, nil
+}
+
+// ParseDocumentWithOptions parses ini formatted input into a Document, using
+// the options to determine the syntax. See ParseDocument for more.
+func ParseDocumentWithOptions(r io.Reader, opts Options) (*Document, error) {
+	p, err := newParser(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.doc = &Document{opts: p.opts, encoding: p.encoding}
+	if err := p.parse(); err != nil {
+		if errs, ok := err.(ErrorList); ok {
+			return p.doc, errs
+		}
+		return nil, err
+	}
+
+	return p.doc, nil
+}
+
+// WithDefaults returns a copy of the options with the defaults set, or an error
+// if the options are invalid.
+func (o Options) withDefaults() (Options, error) {
+	if o.Separators == "" {
+		o.Separators = defaultSeparators
+	}
+	if o.CommentChars == "" {
+		o.CommentChars = defaultCommentChars
+	}
+	if o.MaxIncludeDepth <= 0 {
+		o.MaxIncludeDepth = defaultMaxIncludeDepth
+	}
+
+	for _, chars := range []string{o.Separators, o.CommentChars} {
+		for _, r := range chars {
+			if r >= utf8.RuneSelf || unicode.IsSpace(r) || !unicode.IsPrint(r) ||
+				r == rune(sectionStart) || r == rune(escape) ||
+				r == rune(doubleQuote) || r == rune(singleQuote) {
+				return o, fmt.Errorf("ini: invalid separator or comment character %q", r)
+			}
+		}
+	}
+	if !o.Encoding.isValid() {
+		return o, fmt.Errorf("ini: invalid encoding %s", o.Encoding)
+	}
+	if strings.ContainsAny(o.Separators, o.CommentChars) {
+		return o, errors.New("ini: separators and comment characters overlap")
+	}
+	return o, nil
+}
+
+// FoldSection returns the section name used to compare section names, see
+// Options.CaseInsensitiveSections.
+func (o *Options) foldSection(name string) string {
+	if !o.CaseInsensitiveSections {
+		return name
+	}
+
+	var folded strings.Builder
+	var inQuotes, escaped bool
+	for _, r := range name {
+		switch {
+		case escaped:
+			escaped = false
+		case r == rune(doubleQuote):
+			inQuotes = !inQuotes
+		case r == rune(escape) && inQuotes:
+			escaped = true
+		case !inQuotes:
+			r = unicode.ToLower(r)
+		}
+		folded.WriteRune(r)
+	}
+	return folded.String()
+}
+
+// FoldKey returns the key used to compare keys, see
+// Options.CaseInsensitiveKeys.
+func (o *Options) foldKey(key string) string {
+	if !o.CaseInsensitiveKeys {
+		return key
+	}
+	return strings.ToLower(key)
+}
+
+// SameKey checks if the keys are the same, see Options.CaseInsensitiveKeys.
+func (o *Options) sameKey(a, b string) bool {
+	return o.foldKey(a) == o.foldKey(b)
+}
+
+func (o *Options) isSeparator(b byte) bool {
+	return strings.IndexByte(o.Separators, b) != -1
+}
+
+func (o *Options) isCommentStart(b byte) bool {
+	return strings.IndexByte(o.CommentChars, b) != -1
+}
+
+// IsInlineComment checks if an inline comment starts at line[i].
+func (o *Options) isInlineComment(line []byte, i int) bool {
+	return !o.NoInlineComments && o.isCommentStart(line[i]) &&
+		(!o.InlineCommentSpace || (i > 0 && unicode.IsSpace(rune(line[i-1]))))
+}
+
+// SeparatorError returns the error message for when a separator is expected.
+func (o *Options) separatorError(line []byte) string {
+	if len(o.Separators) == 1 {
+		return fmt.Sprintf("unexpected %q, expected the separator %q",
+			getFullRune(line), o.Separators)
+	}
+	return fmt.Sprintf("unexpected %q, expected one of the separators %q",
+		getFullRune(line), o.Separators)
+}
@@ -0,0 +1,282 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ParseOptionsTest struct {
+	content string
+	opts    Options
+	config  Config
+}
+
+func TestParseWithOptions(t *testing.T) {
+	t.Parallel()
+	colon := Options{Separators: ":"}
+	both := Options{Separators: "=:"}
+	noInline := Options{NoInlineComments: true}
+	space := Options{InlineCommentSpace: true}
+	hash := Options{CommentChars: "#"}
+	merge := Options{DuplicateSections: SectionMerge}
+	lastWins := Options{DuplicateKeys: KeyLastWins}
+	firstWins := Options{DuplicateKeys: KeyFirstWins}
+	collectAll := Options{DuplicateKeys: KeyCollectAll}
+	noQuotes := Options{NoQuotes: true}
+	backslash := Options{BackslashContinuation: true}
+	indented := Options{IndentedContinuation: true}
+	noValue := Options{AllowNoValue: true}
+	ignoreCase := Options{CaseInsensitiveSections: true, CaseInsensitiveKeys: true,
+		DuplicateSections: SectionMerge, DuplicateKeys: KeyLastWins, IndentedContinuation: true}
+
+	tests := []ParseOptionsTest{
+		{"key: value", colon, Config{Global: {"key": "value"}}}, // Separators.
+		{"key=value: 1", colon, Config{Global: {"key=value": "1"}}},
+		{"key: value=1", both, Config{Global: {"key": "value=1"}}},
+		{"key= value:1", both, Config{Global: {"key": "value:1"}}},
+		{`"ke:y" : value`, both, Config{Global: {"ke:y": "value"}}},
+		{"key=value ; comment", noInline, Config{Global: {"key": "value ; comment"}}}, // No inline comments.
+		{"key=pass;word#1", noInline, Config{Global: {"key": "pass;word#1"}}},
+		{"; comment\nkey=value", noInline, Config{Global: {"key": "value"}}},
+		{"[section]", noInline, Config{Global: {}, "section": {}}},
+		{"url=http://example.com/#fragment", space, // Inline comments need space.
+			Config{Global: {"url": "http://example.com/#fragment"}}},
+		{"key=pass;word ; comment", space, Config{Global: {"key": "pass;word"}}},
+		{"key=;value", space, Config{Global: {"key": ";value"}}},
+		{"key=value\t# comment", space, Config{Global: {"key": "value"}}},
+		{"[section] ; comment", space, Config{Global: {}, "section": {}}},
+		{"key=pass;word # comment", hash, Config{Global: {"key": "pass;word"}}}, // Comment characters.
+		{"; key=value", hash, Config{Global: {"; key": "value"}}},
+		{"[a]\nkey=1\n[b]\n[a]\nkey2=2", merge, // Duplicate sections.
+			Config{Global: {}, "a": {"key": "1", "key2": "2"}, "b": {}}},
+		{"key=1\nkey=2\nkey=3", lastWins, Config{Global: {"key": "3"}}}, // Duplicate keys.
+		{"key=1\nkey=2\n\tcontinued", Options{DuplicateKeys: KeyLastWins, IndentedContinuation: true},
+			Config{Global: {"key": "2\ncontinued"}}},
+		{"key=1\nkey=2\nkey=3", firstWins, Config{Global: {"key": "1"}}},
+		{"key=1\nkey=2\n\tcontinued", Options{DuplicateKeys: KeyFirstWins, IndentedContinuation: true},
+			Config{Global: {"key": "1"}}},
+		{"key=1\nkey=2\nkey=3", collectAll, Config{Global: {"key": "3"}}},
+		{"key=1\n[section]\nkey=2", firstWins, Config{Global: {"key": "1"},
+			"section": {"key": "2"}}},
+		{"[a]\nkey=1\n[a]\nkey=2", Options{DuplicateSections: SectionMerge,
+			DuplicateKeys: KeyFirstWins}, Config{Global: {}, "a": {"key": "1"}}},
+		{`key = "value" ; comment`, noQuotes, Config{Global: {"key": `"value"`}}}, // No quotes.
+		{`"key" = 'it\'s'`, noQuotes, Config{Global: {`"key"`: `'it\'s'`}}},
+		{`path = C:\dir\`, noQuotes, Config{Global: {"path": `C:\dir\`}}},
+		{"key = a \\\n\tb", backslash, Config{Global: {"key": "a b"}}}, // Continuation.
+		{`path = "C:\\dir\\"` + "\nother = 1", backslash, Config{Global: {"path": `C:\dir\`, "other": "1"}}},
+		{"key = a\n\t; comment\n\tb", indented, Config{Global: {"key": "a\nb"}}},
+		{"key = a\n  [section]\n  b = 1", indented, Config{Global: {"key": "a"}, "section": {"b": "1"}}},
+		{"[a]\n\tkey = a\n\tkey2 = b", indented, Config{Global: {}, "a": {"key": "a", "key2": "b"}}},
+		{"key = a\n\tb = c", Options{}, Config{Global: {"key": "a", "b": "c"}}},
+		{"key = v\n  [section]\n  a = 1", Options{}, Config{Global: {"key": "v"}, "section": {"a": "1"}}},
+		{`path = C:\dir\` + "\nother = 1", noQuotes, Config{Global: {"path": `C:\dir\`, "other": "1"}}},
+		{"debug\nkey = value", noValue, Config{Global: {"debug": "", "key": "value"}}}, // No value.
+		{"debug ; comment\n\"quoted key\"", noValue, Config{Global: {"debug": "", "quoted key": ""}}},
+		{"debug\nkey = value", Options{AllowNoValue: true, NoValue: "true"},
+			Config{Global: {"debug": "true", "key": "value"}}},
+		{"[Foo]\nKey=1\n[FOO]\nkey=2\n\tcontinued", ignoreCase, // Case-insensitive.
+			Config{Global: {}, "Foo": {"Key": "2\ncontinued"}}},
+		{"[Ä]\nÖ=1\n[ä]\nö=2", ignoreCase, Config{Global: {}, "Ä": {"Ö": "2"}}},
+		{"[remote \"Origin\"]\n[REMOTE \"Origin\"]\n[remote \"origin\"]", ignoreCase,
+			Config{Global: {}, `remote "Origin"`: {}, `remote "origin"`: {}}},
+		{"[a]\n[A]", Options{CaseInsensitiveKeys: true},
+			Config{Global: {}, "a": {}, "A": {}}},
+		{"key=1\nKEY=2", Options{CaseInsensitiveSections: true},
+			Config{Global: {"key": "1", "KEY": "2"}}},
+	}
+
+	for _, test := range tests {
+		config, err := ParseWithOptions(strings.NewReader(test.content), test.opts)
+		if err != nil {
+			t.Fatalf("Unexpected error from ParseWithOptions(%q, %+v): %s",
+				test.content, test.opts, err.Error())
+		}
+
+		if !reflect.DeepEqual(config, test.config) {
+			t.Fatalf("Expected ParseWithOptions(%q, %+v) to return %q, but got %q",
+				test.content, test.opts, test.config, config)
+		}
+	}
+}
+
+func TestParseWithOptionsError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		content string
+		opts    Options
+		errMsg  string
+	}{
+		{`"key" = value`, Options{Separators: ":"},
+			`ini: syntax error on line 1: unexpected "=", expected the separator ":"`},
+		{`"key" value`, Options{Separators: "=:"},
+			`ini: syntax error on line 1: unexpected "v", expected one of the separators "=:"`},
+		{"[section] ; comment", Options{NoInlineComments: true},
+			`ini: syntax error on line 1: unexpected ";" after section closed`},
+		{"[section]; comment", Options{InlineCommentSpace: true},
+			`ini: syntax error on line 1: unexpected ";" after section closed`},
+		{"key=value", Options{Separators: " "},
+			`ini: invalid separator or comment character ' '`},
+		{"key=value", Options{Separators: `"`},
+			`ini: invalid separator or comment character '"'`},
+		{"key=value", Options{CommentChars: "["},
+			`ini: invalid separator or comment character '['`},
+		{"key=value", Options{Separators: "=;"},
+			`ini: separators and comment characters overlap`},
+		{"[a]\n[a]", Options{DuplicateKeys: KeyLastWins},
+			`ini: syntax error on line 2: section "a" already exists`},
+		{"[a]\nkey=1\n[a]\nkey=2", Options{DuplicateSections: SectionMerge},
+			`ini: syntax error on line 4: key "key" already used in section "a"`},
+		{"[a]\n[A]", Options{CaseInsensitiveSections: true},
+			`ini: syntax error on line 2: section "A" already exists`},
+		{"[a]\nkey=1\nKey=2", Options{CaseInsensitiveKeys: true},
+			`ini: syntax error on line 3: key "Key" already used in section "a"`},
+		{"[section\\\n] a", Options{BackslashContinuation: true},
+			"ini: syntax error on line 2: unexpected \"a\" after section closed"},
+		{"key = a \\\nb", Options{},
+			`ini: syntax error on line 2: no separator found`},
+		{"# comment\n\"\"", Options{AllowNoValue: true},
+			`ini: syntax error on line 2: key can't be empty`},
+	}
+
+	for _, test := range tests {
+		_, err := ParseWithOptions(strings.NewReader(test.content), test.opts)
+		if err == nil {
+			t.Fatalf("Expected ParseWithOptions(%q, %+v) to return an error, "+
+				"but didn't get one", test.content, test.opts)
+		} else if err.Error() != test.errMsg {
+			t.Fatalf("Expected ParseWithOptions(%q, %+v) to return error: %q, but got %q",
+				test.content, test.opts, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestParseDocumentWithOptions(t *testing.T) {
+	t.Parallel()
+	content := "key: value # comment\n"
+	opts := Options{Separators: ":=", CommentChars: "#"}
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	if got := doc.String(); got != content {
+		t.Fatalf("Expected %q, but got %q", content, got)
+	}
+
+	setValue(t, doc, Global, "key", "new value")
+	setValue(t, doc, Global, "a:b", "c")
+	setValue(t, doc, Global, "semi", "colon;")
+	expected := "key: new value # comment\n\"a:b\":c\nsemi:colon;\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+
+	content = "debug # comment\n"
+	opts = Options{NoQuotes: true, AllowNoValue: true, SpaceAroundSeparator: true}
+	doc, err = ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %s", err.Error())
+	}
+
+	setValue(t, doc, Global, "debug", "true")
+	setValue(t, doc, Global, "path", `"C:\dir"`)
+	expected = "debug = true # comment\npath = \"C:\\dir\"\n"
+	if got := doc.String(); got != expected {
+		t.Fatalf("Expected %q, but got %q", expected, got)
+	}
+}
+
+func TestDocumentDuplicateKeys(t *testing.T) {
+	t.Parallel()
+	content := "key=1\nkey=2\n"
+	tests := []struct {
+		policy   KeyPolicy
+		value    string
+		expected string
+	}{
+		{KeyLastWins, "2", "key=1\nkey=3\n"},
+		{KeyFirstWins, "1", "key=3\nkey=2\n"},
+		{KeyCollectAll, "2", "key=1\nkey=3\n"},
+	}
+
+	for _, test := range tests {
+		opts := Options{DuplicateKeys: test.policy}
+		doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing document: %s", err.Error())
+		}
+
+		if value, _ := doc.Get(Global, "key"); value != test.value {
+			t.Fatalf("Expected Document.Get to return %q, but got %q",
+				test.value, value)
+		} else if value := doc.Config()[Global]["key"]; value != test.value {
+			t.Fatalf("Expected Document.Config to hold %q, but got %q",
+				test.value, value)
+		}
+
+		setValue(t, doc, Global, "key", "3")
+		if got := doc.String(); got != test.expected {
+			t.Fatalf("Expected %q, but got %q", test.expected, got)
+		}
+	}
+}
+
+func TestParseRecover(t *testing.T) {
+	t.Parallel()
+	content := "key = value\nbroken\n[section\n[section]\nkey = \"unclosed\n" +
+		"key = 1\nkey = 2\n[child : missing]\n[other]\nkey = value"
+	opts := Options{Recover: true, InheritSections: true}
+
+	expectedErrors := []string{
+		"ini: syntax error on line 2: no separator found",
+		"ini: syntax error on line 3: unclosed section",
+		"ini: syntax error on line 5: quote not closed",
+		`ini: syntax error on line 7: key "key" already used in section "section"`,
+		`ini: syntax error on line 8: unknown parent section "missing"`,
+	}
+	expected := Config{
+		Global:    {"key": "value"},
+		"section": {"key": "1"},
+		"child":   {},
+		"other":   {"key": "value"},
+	}
+
+	c, err := ParseWithOptions(strings.NewReader(content), opts)
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("Expected an ErrorList, but got %v", err)
+	} else if len(errs) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors, but got %d: %v", len(expectedErrors), len(errs), errs)
+	}
+	for i, err := range errs {
+		if !IsSyntaxError(err) || err.Error() != expectedErrors[i] {
+			t.Fatalf("Expected error %d to be %q, but got %q", i, expectedErrors[i], err.Error())
+		}
+	}
+	if got := err.Error(); got != expectedErrors[0]+" (and 4 more errors)" {
+		t.Fatalf("Unexpected error message %q", got)
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Fatalf("Expected the partial config to be %v, but got %v", expected, c)
+	}
+
+	doc, err := ParseDocumentWithOptions(strings.NewReader(content), opts)
+	if _, ok := err.(ErrorList); !ok {
+		t.Fatalf("Expected an ErrorList, but got %v", err)
+	} else if got := doc.String(); got != content {
+		t.Fatalf("Expected Document.String() to return %q, but got %q", content, got)
+	} else if kind := doc.Nodes()[1].Kind; kind != ErrorNode {
+		t.Fatalf("Expected the invalid line to be an error node, but got a %s node", kind)
+	}
+
+	// Without errors no ErrorList is returned.
+	if _, err := ParseWithOptions(strings.NewReader("key = value"), opts); err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+}
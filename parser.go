@@ -7,120 +7,595 @@ package ini
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
 const (
-	commentStart1 byte = ';'
-	commentStart2 byte = '#'
-	separator     byte = '='
-	sectionStart  byte = '['
-	sectionEnd    byte = ']'
-	escape        byte = '\\'
-	doubleQuote   byte = '"'
-	singleQuote   byte = '\''
-	nilQuote      byte = 0
+	sectionStart byte = '['
+	sectionEnd   byte = ']'
+	escape       byte = '\\'
+	doubleQuote  byte = '"'
+	singleQuote  byte = '\''
+	nilQuote     byte = 0
 )
 
 type parser struct {
 	Config         Config
-	scanner        *bufio.Scanner
+	opts           *Options
+	lines          *lineReader
 	currentSection string
+
+	// The last key-value node, the indentation of its first line and whether
+	// or not it's stored in Config, used for indented continuation lines.
+	lastKeyValue *Node
+	lastIndent   int
+	lastStored   bool
+
+	// Document to add all nodes to, may be nil.
+	doc *Document
+
+	// File system to read included files from, nil if includes aren't
+	// supported, and the stack of files being parsed.
+	fsys  fs.FS
+	files []string
+
+	// Names of the sections and keys in Config by their folded names, see
+	// Options.CaseInsensitiveSections and Options.CaseInsensitiveKeys.
+	sectionNames map[string]string
+	keyNames     map[string]map[string]string
+
+	// Parents of the sections, see Options.InheritSections.
+	parents map[string]sectionParent
+
+	// Total number of bytes read, see Limits.MaxBytes.
+	bytesRead int64
+
+	// Encoding of the input, see Options.Encoding.
+	encoding Encoding
+
+	// Whether or not the parser is used by a Scanner, in which case the
+	// sections and keys aren't stored in Config.
+	stream bool
+
+	// Errors collected in recovery mode, see Options.Recover, and whether or
+	// not a node is added to the document for the current line.
+	errors ErrorList
+	added  bool
 }
 
+// Parse parses all lines and resolves the inheritance of sections. In
+// recovery mode it returns an ErrorList if any syntax errors are found.
 func (p *parser) parse() error {
-	var currentlineNumber int
+	if err := p.parseLines(); err != nil {
+		return err
+	}
+	if err := p.inherit(); err != nil {
+		return err
+	}
 
-	for p.scanner.Scan() {
-		line := p.scanner.Bytes()
-		currentlineNumber++
+	if len(p.errors) != 0 {
+		return p.errors
+	}
+	return nil
+}
+
+func (p *parser) parseLines() error {
+	for {
+		line, ok := p.lines.next()
+		if !ok {
+			break
+		}
 
+		p.added = false
 		if err := p.handleLine(line); err != nil {
-			return createSyntaxError(currentlineNumber, err.Error())
+			if includedErr, ok := err.(includedError); ok {
+				return includedErr.error
+			} else if limitErr, ok := err.(LimitError); ok {
+				return p.limitError(limitErr, line.lineNumber)
+			}
+
+			if err := p.recover(p.syntaxError(line, err)); err != nil {
+				return err
+			}
+			p.skipLine(line)
 		}
 	}
 
-	if err := p.scanner.Err(); err != nil {
+	return p.readError()
+}
+
+// SkipLine skips the line with a syntax error, but keeps it in the document as
+// an error node.
+func (p *parser) skipLine(line *logicalLine) {
+	p.lastKeyValue = nil
+	if !p.added {
+		p.addNode(line, &Node{Kind: ErrorNode})
+	}
+}
+
+// ReadError returns the error reading the input, if any.
+func (p *parser) readError() error {
+	if err := p.lines.err(); err != nil {
+		if err, ok := err.(LimitError); ok {
+			return p.limitError(err, err.Position.Line)
+		}
 		return fmt.Errorf("ini: error reading: %s", err.Error())
 	}
 	return nil
 }
 
-func (p *parser) handleLine(line []byte) error {
-	line = bytes.TrimSpace(line)
-	if len(line) == 0 {
-		return nil
+func (p *parser) handleLine(line *logicalLine) error {
+	if p.isContinuation(line.line) {
+		return p.continueValue(line)
 	}
 
-	b := line[0]
-	if isCommentStart(b) {
-		return nil
-	} else if b == sectionStart {
-		sectionName, err := parseSection(line)
-		if err != nil {
+	n, err := p.parseLine(line.line)
+	if err != nil {
+		return err
+	}
+
+	// Comments don't end the value of a key, but anything else does.
+	if n.Kind != CommentNode {
+		p.lastKeyValue = nil
+	}
+	switch n.Kind {
+	case SectionNode:
+		if p.stream {
+			p.currentSection = n.Section
+			break
+		}
+		if err := p.updateSection(n.Section); err != nil {
+			return err
+		}
+		n.Section = p.currentSection
+		if err := p.setParent(n.Section, n.Parent, line.lineNumber); err != nil {
 			return err
 		}
-		return p.updateSection(sectionName)
+	case KeyValueNode:
+		if err := p.opts.Limits.checkValue(n.Value); err != nil {
+			return err
+		}
+		var stored bool
+		if !p.stream {
+			stored, err = p.addKeyValue(n.Key, n.Value)
+			if err != nil {
+				return err
+			}
+		}
+		n.Section = p.currentSection
+		p.lastKeyValue = n
+		p.lastStored = stored
+		p.lastIndent = indentation(line.line)
+	case IncludeNode:
+		p.addNode(line, n)
+		return p.include(n.Key, n.Value)
+	}
+
+	p.addNode(line, n)
+	return nil
+}
+
+// AddNode adds the node, parsed from the line, to the document, if any.
+func (p *parser) addNode(line *logicalLine, n *Node) {
+	if p.doc == nil {
+		return
+	}
+
+	n.raw = line.raw
+	n.File = p.file()
+	n.Line = line.lineNumber
+	if n.Kind != BlankNode {
+		n.Column = utf8.RuneCount(line.raw[:indentation(line.raw)]) + 1
+	}
+	n.included = len(p.files) > 1
+	p.added = true
+	if line.isJoined() {
+		// The indices point into the joined line, not the raw line.
+		n.valueStart, n.valueEnd = -1, -1
+	}
+	p.doc.nodes = append(p.doc.nodes, n)
+}
+
+// SyntaxError creates a syntax error for the error found in the line. If it's
+// an offsetError the error includes the column and source line.
+func (p *parser) syntaxError(line *logicalLine, err error) SyntaxError {
+	synErr := SyntaxError{
+		File:       p.file(),
+		LineNumber: line.lineNumber,
+		Message:    err.Error(),
+	}
+	if err, ok := err.(offsetError); ok {
+		lineNumber, offset := line.position(err.offset)
+		source := line.physical(lineNumber)
+		if offset > len(source) {
+			offset = len(source)
+		}
+		synErr.LineNumber = lineNumber
+		synErr.Column = utf8.RuneCount(source[:offset]) + 1
+		synErr.Source = string(source)
+	}
+	return synErr
+}
+
+// Recover adds the syntax error to the list of errors in recovery mode,
+// otherwise it returns the error.
+func (p *parser) recover(err SyntaxError) error {
+	if !p.opts.Recover {
+		return err
+	}
+	p.errors = append(p.errors, err)
+	return nil
+}
+
+// File returns the name of the file being parsed, or an empty string if the
+// input isn't a file.
+func (p *parser) file() string {
+	if len(p.files) == 0 {
+		return ""
 	}
+	return p.files[len(p.files)-1]
+}
 
-	key, value, err := parseKeyValue(line)
+// IsContinuation checks if the line is an indented continuation of the value
+// of the last key-value pair, like so:
+//
+//	key = first line
+//		second line
+//
+// The line must be indented further than the line of the key and can't be a
+// comment or section header, see Options.IndentedContinuation.
+func (p *parser) isContinuation(line []byte) bool {
+	if p.lastKeyValue == nil || !p.opts.IndentedContinuation {
+		return false
+	}
+
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) != 0 && !p.opts.isCommentStart(trimmed[0]) &&
+		trimmed[0] != sectionStart && indentation(line) > p.lastIndent
+}
+
+// ContinueValue adds the value on the continuation line to the value of the
+// last key-value pair, separated by a new line.
+func (p *parser) continueValue(line *logicalLine) error {
+	indent := indentation(line.line)
+	value, _, _, err := p.opts.parsePart(line.line, indent, false)
 	if err != nil {
 		return err
 	}
-	return p.addKeyValue(key, value)
+
+	n := p.lastKeyValue
+	n.Value += "\n" + string(value)
+	if err := p.opts.Limits.checkValue(n.Value); err != nil {
+		return err
+	}
+	if p.lastStored {
+		p.Config[n.Section][p.keyName(n.Section, n.Key)] = n.Value
+	}
+
+	if p.doc != nil {
+		// Comments between the lines of the value become part of the node.
+		nodes := p.doc.nodes
+		for len(nodes) != 0 && nodes[len(nodes)-1] != n {
+			nodes = nodes[:len(nodes)-1]
+		}
+		for _, comment := range p.doc.nodes[len(nodes):] {
+			n.raw = append(n.raw, comment.raw...)
+		}
+		p.doc.nodes = nodes
+
+		n.raw = append(n.raw, line.raw...)
+		n.valueStart, n.valueEnd = -1, -1
+	}
+	return nil
+}
+
+// ParseLine parses a single line, including the line ending, into a node. The
+// section of key-value nodes is not set. The indices in the returned node
+// point into the untrimmed line.
+func (o *Options) parseLine(line []byte) (*Node, error) {
+	trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+	indent := len(line) - len(trimmed)
+	trimmed = bytes.TrimRightFunc(trimmed, unicode.IsSpace)
+	if len(trimmed) == 0 {
+		return &Node{Kind: BlankNode}, nil
+	}
+
+	b := trimmed[0]
+	var n *Node
+	var err error
+	if o.isCommentStart(b) {
+		return &Node{Kind: CommentNode, Comment: string(trimmed)}, nil
+	} else if b == sectionStart {
+		n, err = o.parseSection(trimmed)
+	} else {
+		n, err = o.parseKeyValue(trimmed)
+	}
+	if err != nil {
+		if err, ok := err.(offsetError); ok {
+			err.offset += indent
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if n.valueStart >= 0 {
+		n.valueStart += indent
+		n.valueEnd += indent
+	}
+	return n, nil
 }
 
 func (p *parser) updateSection(sectionName string) error {
-	if _, ok := p.Config[sectionName]; ok {
+	p.currentSection = p.sectionName(sectionName)
+	if _, ok := p.Config[p.currentSection]; ok {
+		if p.opts.DuplicateSections == SectionMerge {
+			return nil
+		}
 		return fmt.Errorf("section %q already exists", sectionName)
 	}
-	p.currentSection = sectionName
+	// The global section doesn't count.
+	if err := p.opts.Limits.checkSections(len(p.Config)); err != nil {
+		return err
+	}
 	p.Config[sectionName] = map[string]string{}
+	if p.opts.CaseInsensitiveSections {
+		p.sectionNames[p.opts.foldSection(sectionName)] = sectionName
+	}
 	return nil
 }
 
-func (p *parser) addKeyValue(key, value string) error {
+// AddKeyValue adds the key-value pair to the current section. It returns false
+// if the value isn't stored, because an earlier value takes precedence.
+func (p *parser) addKeyValue(key, value string) (bool, error) {
 	sectionName := p.currentSection
-	if _, ok := p.Config[sectionName][key]; ok {
-		if sectionName == Global {
-			sectionName = globalName
+	name := p.keyName(sectionName, key)
+	if _, ok := p.Config[sectionName][name]; ok {
+		switch p.opts.DuplicateKeys {
+		case KeyFirstWins:
+			return false, nil
+		case KeyLastWins, KeyCollectAll:
+		default:
+			if sectionName == Global {
+				sectionName = globalName
+			}
+			return false, fmt.Errorf("key %q already used in section %q", key, sectionName)
 		}
-		return fmt.Errorf("key %q already used in section %q", key, sectionName)
 	}
 
-	p.Config[sectionName][key] = value
-	return nil
+	if _, ok := p.Config[sectionName][name]; !ok {
+		if err := p.opts.Limits.checkKeys(len(p.Config[sectionName]) + 1); err != nil {
+			return false, err
+		}
+		if p.opts.CaseInsensitiveKeys {
+			if p.keyNames[sectionName] == nil {
+				p.keyNames[sectionName] = map[string]string{}
+			}
+			p.keyNames[sectionName][p.opts.foldKey(name)] = name
+		}
+	}
+	p.Config[sectionName][name] = value
+	return true, nil
+}
+
+// SectionName returns the name under which the section is stored in Config,
+// which is the spelling of the first header of the section if section names
+// are case-insensitive.
+func (p *parser) sectionName(name string) string {
+	if canonical, ok := p.sectionNames[p.opts.foldSection(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+// KeyName returns the name under which the key in the section is stored in
+// Config, see sectionName.
+func (p *parser) keyName(section, key string) string {
+	if canonical, ok := p.keyNames[section][p.opts.foldKey(key)]; ok {
+		return canonical
+	}
+	return key
 }
 
 // Parse parses ini formatted input.
 //
+// Values are a single line, continuation lines can be enabled using
+// Options.BackslashContinuation and Options.IndentedContinuation.
+//
+// Quoted keys and values can contain the same escape sequences as Go strings,
+// such as "\n", "\t", "\\", "\xNN" and "\uNNNN". This makes the output of
+// `Config.WriteTo` parse back into the same configuration.
+//
+// The returned Config only holds the key-value pairs, not where they're
+// found. Use ParseWithMetadata to also get the positions of the keys, see
+// Metadata.Position, which Metadata.Decode includes in a DecodeError.
+//
 // Note: the reader already gets buffered, so there is no need to buffer it
 // yourself.
 func Parse(r io.Reader) (Config, error) {
-	p := newParser(r)
-	if err := p.parse(); err != nil {
+	return ParseWithOptions(r, Options{})
+}
+
+func newParser(r io.Reader, opts Options) (*parser, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
 		return nil, err
 	}
 
-	return p.Config, nil
-}
-
-func newParser(r io.Reader) *parser {
-	return &parser{
+	p := &parser{
 		Config:         Config{Global: {}},
+		opts:           &opts,
 		currentSection: Global,
-		scanner:        bufio.NewScanner(r),
+		sectionNames:   map[string]string{},
+		keyNames:       map[string]map[string]string{},
+	}
+	r, p.encoding = decodeReader(r, opts.Encoding)
+	p.lines = newLineReader(r, &opts, &p.bytesRead)
+	return p, nil
+}
+
+// ScanLines is a split function for a `bufio.Scanner`, much like
+// `bufio.ScanLines`, but it keeps the line ending as part of the line. This
+// allows a Document to be written back exactly as it was read.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
 	}
+	return 0, nil, nil
 }
 
-// Assumes the first character is always an opening bracket.
-func parseSection(line []byte) (string, error) {
+// LineReader reads logical lines, joining physical lines that end with a
+// backslash with the next line, like so:
+//
+//	key = first \
+//		second
+//
+// Results in the value "first second".
+type lineReader struct {
+	scanner    *bufio.Scanner
+	opts       *Options
+	lineNumber int
+	bytesRead  *int64
+}
+
+// LogicalLine is one or more physical lines joined by backslash continuations.
+type logicalLine struct {
+	// The joined line, without the backslashes and line endings of the joined
+	// lines and the indentation of the following lines.
+	line []byte
+	// The physical lines as read, including the line endings.
+	raw []byte
+	// Line number of the first physical line.
+	lineNumber int
+	// Offsets in line at which each of the following physical lines start,
+	// and the length of the trimmed indentation of those lines.
+	starts  []int
+	indents []int
+}
+
+// NewLineReader creates a new lineReader, bytesRead is the total number of
+// bytes read by all readers of the parser, see Limits.MaxBytes.
+func newLineReader(r io.Reader, opts *Options, bytesRead *int64) *lineReader {
+	lr := &lineReader{opts: opts, bytesRead: bytesRead}
+	lr.scanner = bufio.NewScanner(r)
+	// Lines can be of any length, unless limited by Limits.MaxLineLength.
+	lr.scanner.Buffer(nil, maxInt)
+	lr.scanner.Split(lr.split)
+	return lr
+}
+
+func (lr *lineReader) next() (*logicalLine, bool) {
+	var line *logicalLine
+	for lr.scanner.Scan() {
+		physical := lr.scanner.Bytes()
+		lr.lineNumber++
+
+		if line == nil {
+			// The scanner reuses its buffer, so we need our own copies.
+			line = &logicalLine{
+				line:       append([]byte(nil), physical...),
+				raw:        append([]byte(nil), physical...),
+				lineNumber: lr.lineNumber,
+			}
+		} else {
+			line.raw = append(line.raw, physical...)
+			line.starts = append(line.starts, len(line.line))
+			line.indents = append(line.indents, indentation(physical))
+			line.line = append(line.line, physical[indentation(physical):]...)
+		}
+
+		if !lr.opts.endsWithContinuation(line.line) {
+			return line, true
+		}
+
+		// Drop the backslash and the line ending.
+		end := bytes.LastIndexByte(line.line, escape)
+		line.line = line.line[:end]
+	}
+
+	if line != nil {
+		// The last line ended with a backslash, but there is nothing to join.
+		line.line = append(line.line, escape)
+		return line, true
+	}
+	return nil, false
+}
+
+func (lr *lineReader) err() error {
+	return lr.scanner.Err()
+}
+
+// IsJoined checks if the logical line exists of multiple physical lines.
+func (l *logicalLine) isJoined() bool {
+	return len(l.starts) != 0
+}
+
+// Position returns the line number of the physical line at which the offset in
+// the logical line is and the offset in that physical line.
+func (l *logicalLine) position(offset int) (lineNumber, lineOffset int) {
+	lineNumber, lineOffset = l.lineNumber, offset
+	for i, start := range l.starts {
+		if offset < start {
+			break
+		}
+		lineNumber, lineOffset = l.lineNumber+i+1, offset-start+l.indents[i]
+	}
+	return lineNumber, lineOffset
+}
+
+// Physical returns the physical line with the line number, without the line
+// ending.
+func (l *logicalLine) physical(lineNumber int) []byte {
+	lines := bytes.SplitAfter(l.raw, []byte("\n"))
+	return bytes.TrimRight(lines[lineNumber-l.lineNumber], "\r\n")
+}
+
+// EndsWithContinuation checks if the line ends with an unescaped backslash.
+// Comments can't be continued.
+func (o *Options) endsWithContinuation(line []byte) bool {
+	if !o.BackslashContinuation {
+		return false
+	}
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 || o.isCommentStart(trimmed[0]) {
+		return false
+	}
+
+	var n int
+	for i := len(trimmed) - 1; i >= 0 && trimmed[i] == escape; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// Indentation returns the number of whitespace bytes at the start of the line.
+func indentation(line []byte) int {
+	return len(line) - len(bytes.TrimLeftFunc(line, unicode.IsSpace))
+}
+
+// Assumes the first character is always an opening bracket and the line is
+// trimmed.
+func (o *Options) parseSection(line []byte) (*Node, error) {
+	if !o.NoQuotes {
+		if n, ok, err := o.parseQuotedSection(line); ok {
+			return n, err
+		}
+	}
+
 	var end int
 	var sectionEnded bool
+	var comment string
 
 	// Skipping the opening bracket.
 	for i, l := 1, len(line); i < l; i++ {
@@ -130,105 +605,273 @@ func parseSection(line []byte) (string, error) {
 			sectionEnded = true
 			end = i
 			continue
-		} else if isCommentStart(b) && sectionEnded {
+		} else if sectionEnded && o.isInlineComment(line, i) {
+			comment = string(line[i:])
 			break
 		} else if sectionEnded && !unicode.IsSpace(rune(b)) {
-			return "", fmt.Errorf("unexpected %q after section closed",
-				getFullRune(line[i:]))
+			return nil, offsetError{i, fmt.Sprintf("unexpected %q after section closed",
+				getFullRune(line[i:]))}
 		}
 	}
 
 	if !sectionEnded {
-		return "", errors.New("unclosed section")
+		return nil, offsetError{len(line), "unclosed section"}
 	}
 
 	section := string(bytes.TrimSpace(line[1:end]))
+	var parent string
+	if o.InheritSections {
+		if i := strings.Index(section, parentSeparator); i != -1 {
+			parent = strings.TrimSpace(section[i+1:])
+			section = strings.TrimSpace(section[:i])
+			if len(parent) == 0 {
+				return nil, offsetError{end, "parent section can't be empty"}
+			}
+		}
+	}
 	if len(section) == 0 {
-		return "", errors.New("section can't be empty")
+		return nil, offsetError{1, "section can't be empty"}
 	}
 
-	return section, nil
+	return &Node{Kind: SectionNode, Section: section, Parent: parent, Comment: comment}, nil
 }
 
-func parseKeyValue(line []byte) (key, value string, err error) {
-	var values [2][]byte
-	var areQuoted [2]bool
-	var hasSeparator bool
+// ParseQuotedSection parses a section header in which the complete name is
+// quoted, e.g. `["weird ] name"]`, using the same quotes and escape sequences
+// as keys. It returns false if the name isn't quoted, or if the quoted name
+// isn't followed by the closing bracket, e.g. `["a" "b"]`, in which case the
+// quotes are part of the name. Assumes the first character is always an
+// opening bracket and the line is trimmed.
+func (o *Options) parseQuotedSection(line []byte) (*Node, bool, error) {
+	i := 1 + indentation(line[1:])
+	if i >= len(line) || (line[i] != doubleQuote && line[i] != singleQuote) {
+		return nil, false, nil
+	}
 
-	// Keep track of the byte number for both the key and value.
-	for i, valueNumber := 0, 0; valueNumber <= 1; valueNumber++ {
-		var isQuoted, isEscaped, nextShouldBeSeparator bool
-		var usedQuote byte
+	quote, quoteStart := line[i], i
+	var name []byte
+	for i++; ; i++ {
+		if i >= len(line) {
+			return nil, false, nil
+		} else if line[i] == quote {
+			break
+		} else if line[i] != escape {
+			name = append(name, line[i])
+			continue
+		}
 
-		for ; i < len(line); i++ {
-			b := line[i]
-			isSpace := unicode.IsSpace(rune(b))
+		decoded, n, err := unescape(line[i:])
+		if err != nil {
+			return nil, false, nil
+		}
+		name = append(name, decoded...)
+		i += n - 1
+	}
+
+	// Skip the closing quote.
+	i++
+	i += indentation(line[i:])
+	var parent string
+	if o.InheritSections && i < len(line) && line[i] == parentSeparator[0] {
+		end := bytes.IndexByte(line[i:], sectionEnd)
+		if end == -1 {
+			return nil, false, nil
+		}
+		parent = string(bytes.TrimSpace(line[i+1 : i+end]))
+		if len(parent) == 0 {
+			return nil, true, offsetError{i + end, "parent section can't be empty"}
+		}
+		i += end
+	}
+	if i >= len(line) || line[i] != sectionEnd {
+		return nil, false, nil
+	}
+
+	// Skip the closing bracket.
+	i++
+	i += indentation(line[i:])
+	var comment string
+	if i < len(line) {
+		if line[i] == sectionEnd {
+			return nil, false, nil
+		} else if !o.isInlineComment(line, i) {
+			return nil, true, offsetError{i, fmt.Sprintf("unexpected %q after section closed",
+				getFullRune(line[i:]))}
+		}
+		comment = string(line[i:])
+	}
+
+	if len(name) == 0 {
+		return nil, true, offsetError{quoteStart, "section can't be empty"}
+	}
+	return &Node{Kind: SectionNode, Section: string(name), Parent: parent, Comment: comment}, true, nil
+}
+
+// Assumes the line is trimmed.
+func (o *Options) parseKeyValue(line []byte) (*Node, error) {
+	key, _, i, err := o.parsePart(line, 0, true)
+	if err != nil {
+		return nil, err
+	} else if i >= len(line) || !o.isSeparator(line[i]) {
+		if !o.AllowNoValue {
+			return nil, offsetError{len(line), "no separator found"}
+		} else if len(key) == 0 {
+			return nil, offsetError{0, "key can't be empty"}
+		}
+		n := &Node{Kind: KeyValueNode, Key: string(key), Value: o.NoValue,
+			valueStart: -1, valueEnd: -1}
+		if i < len(line) {
+			n.Comment = string(line[i:])
+		}
+		return n, nil
+	}
+
+	valueStart := i + 1 // Skip the separator.
+	value, _, commentStart, err := o.parsePart(line, valueStart, false)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		Kind:  KeyValueNode,
+		Key:   string(key),
+		Value: string(value),
+	}
+	if len(n.Key) == 0 {
+		return nil, offsetError{0, "key can't be empty"}
+	}
+
+	// The raw value, including any quotes, without the surrounding whitespace.
+	rawValue := line[valueStart:commentStart]
+	n.valueStart = valueStart + len(rawValue) -
+		len(bytes.TrimLeftFunc(rawValue, unicode.IsSpace))
+	n.valueEnd = valueStart + len(bytes.TrimRightFunc(rawValue, unicode.IsSpace))
+	if n.valueEnd < n.valueStart {
+		n.valueEnd = n.valueStart
+	}
+	if commentStart < len(line) {
+		n.Comment = string(line[commentStart:])
+	}
+	return n, nil
+}
+
+// ParsePart parses a key, if isKey is true, or a value starting at line[i].
+// Keys end at the separator, values end at the start of a comment or the end
+// of the line. If keys without a value are allowed keys also end at the start
+// of a comment. It returns the parsed bytes, whether or not the part was quoted
+// and the index at which parsing stopped.
+func (o *Options) parsePart(line []byte, i int, isKey bool) ([]byte, bool, int, error) {
+	var part []byte
+	var isQuoted, wasQuoted, isEscaped, nextShouldBeSeparator bool
+	var usedQuote byte
+	var quoteStart int
+
+	for ; i < len(line); i++ {
+		b := line[i]
+		isSpace := unicode.IsSpace(rune(b))
 
-			if areQuoted[valueNumber] && !isQuoted && isSpace {
-				// Quoted value with whitespace after the closing quote.
+		if wasQuoted && !isQuoted && isSpace {
+			// Quoted value with whitespace after the closing quote.
+			continue
+		} else if nextShouldBeSeparator && !isSpace && !o.isSeparator(b) {
+			return nil, false, i, offsetError{i, o.separatorError(line[i:])}
+		} else if (b == doubleQuote || b == singleQuote) && !isEscaped && !o.NoQuotes {
+			if !isQuoted {
+				isQuoted = true
+				wasQuoted = true
+				part = []byte{}
+				usedQuote = b
+				quoteStart = i
 				continue
-			} else if nextShouldBeSeparator && !isSpace && b != separator {
-				return "", "", fmt.Errorf("unexpected %q, expected the separator %q",
-					getFullRune(line[i:]), string(separator))
-			} else if (b == doubleQuote || b == singleQuote) && !isEscaped {
-				if !isQuoted {
-					isQuoted = true
-					areQuoted[valueNumber] = true
-					values[valueNumber] = []byte{}
-					usedQuote = b
-					continue
-				} else if b == usedQuote {
-					isQuoted = false
-					usedQuote = nilQuote
-					if valueNumber == 0 {
-						nextShouldBeSeparator = true
-					}
-					continue
+			} else if b == usedQuote {
+				isQuoted = false
+				usedQuote = nilQuote
+				nextShouldBeSeparator = isKey
+				continue
+			}
+		} else if !isQuoted && (!isKey || o.AllowNoValue) && o.isInlineComment(line, i) {
+			break
+		} else if b == escape && !isEscaped && !o.NoQuotes {
+			if isQuoted {
+				decoded, n, err := unescape(line[i:])
+				if err != nil {
+					return nil, false, i, offsetError{i, err.Error()}
 				}
-			} else if isCommentStart(b) && !isQuoted && hasSeparator {
-				break
-			} else if b == escape && !isEscaped {
-				isEscaped = true
+				part = append(part, decoded...)
+				i += n - 1
 				continue
-			} else if b == separator && valueNumber == 0 && !isQuoted {
-				hasSeparator = true
-				i++ // skip the separator in the next loop.
-				break
 			}
-
-			isEscaped = false
-			values[valueNumber] = append(values[valueNumber], b)
+			isEscaped = true
+			continue
+		} else if isKey && !isQuoted && o.isSeparator(b) {
+			break
 		}
 
-		if isQuoted {
-			return "", "", errors.New("quote not closed")
-		}
+		isEscaped = false
+		part = append(part, b)
 	}
 
-	if !hasSeparator {
-		return "", "", errors.New("no separator found")
+	if isQuoted {
+		return nil, false, i, offsetError{quoteStart, "quote not closed"}
 	}
 
-	// Only trim extra whitespace if the value weren't quoted.
-	for i := 0; i < len(values); i++ {
-		if !areQuoted[i] {
-			values[i] = bytes.TrimSpace(values[i])
-		}
+	// Only trim extra whitespace if the part wasn't quoted.
+	if !wasQuoted {
+		part = bytes.TrimSpace(part)
+	}
+	return part, wasQuoted, i, nil
+}
+
+// Unescape decodes the escape sequence at the start of s, which must start with
+// a backslash. It supports the same escape sequences as Go, as produced by
+// `strconv.Quote`, e.g. "\n", "\t", "\xNN" and "\uNNNN". Escaped quotes and
+// unknown escape sequences result in the escaped character itself. It returns
+// the decoded bytes and the length of the escape sequence.
+func unescape(s []byte) ([]byte, int, error) {
+	if len(s) < 2 {
+		return nil, len(s), nil
+	}
+
+	switch c := s[1]; c {
+	case 'a', 'b', 'f', 'n', 'r', 't', 'v', 'x', 'u', 'U',
+		'0', '1', '2', '3', '4', '5', '6', '7':
+	default:
+		return []byte{c}, 2, nil
+	}
+
+	// The longest escape sequence is "\UNNNNNNNN".
+	seq := s
+	if len(seq) > 10 {
+		seq = seq[:10]
+	}
+	value, multibyte, tail, err := strconv.UnquoteChar(string(seq), doubleQuote)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid escape sequence %q", getEscape(seq))
 	}
 
-	key = string(values[0])
-	value = string(values[1])
-	if len(key) == 0 {
-		return "", "", errors.New("key can't be empty")
+	n := len(seq) - len(tail)
+	if value < utf8.RuneSelf || !multibyte {
+		return []byte{byte(value)}, n, nil
 	}
-	return key, value, nil
+	var buf [utf8.UTFMax]byte
+	return buf[:utf8.EncodeRune(buf[:], value)], n, nil
+}
+
+// GetEscape returns the (invalid) escape sequence at the start of s, for use
+// in error messages.
+func getEscape(s []byte) string {
+	end := 2
+	for end < len(s) && isHex(s[end]) {
+		end++
+	}
+	return string(s[:end])
+}
+
+func isHex(b byte) bool {
+	return ('0' <= b && b <= '9') || ('a' <= b && b <= 'f') || ('A' <= b && b <= 'F')
 }
 
 func getFullRune(line []byte) string {
 	r, _ := utf8.DecodeRune(line)
 	return string(r)
 }
-
-func isCommentStart(b byte) bool {
-	return b == commentStart1 || b == commentStart2
-}
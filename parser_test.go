@@ -234,6 +234,31 @@ func TestParseKeyValueMisc(t *testing.T) {
 	}
 }
 
+func TestParseEscapes(t *testing.T) {
+	t.Parallel()
+	tests := []ParseTest{
+		{`key="a\nb"`, Config{Global: {"key": "a\nb"}}},
+		{`key='a\tb'`, Config{Global: {"key": "a\tb"}}},
+		{`key="\a\b\f\n\r\t\v"`, Config{Global: {"key": "\a\b\f\n\r\t\v"}}},
+		{`key="a\\b"`, Config{Global: {"key": `a\b`}}},
+		{`key="a\"b"`, Config{Global: {"key": `a"b`}}},
+		{`key="a\'b"`, Config{Global: {"key": `a'b`}}},
+		{`key='a\"b'`, Config{Global: {"key": `a"b`}}},
+		{`key="caf\u00e9"`, Config{Global: {"key": "café"}}},
+		{`key="\U0001F600"`, Config{Global: {"key": "\U0001F600"}}},
+		{`key="\x41\xff"`, Config{Global: {"key": "A\xff"}}},
+		{`key="\101\0001"`, Config{Global: {"key": "A\x001"}}},
+		{`key="\q"`, Config{Global: {"key": "q"}}},
+		{`key="\;"`, Config{Global: {"key": ";"}}},
+		{`"k\te\ny"=value`, Config{Global: {"k\te\ny": "value"}}},
+		{`key=a\nb`, Config{Global: {"key": "anb"}}}, // Only in quotes.
+	}
+
+	if err := testParser(tests); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
 func TestParseSection(t *testing.T) {
 	t.Parallel()
 	tests := []ParseTest{
@@ -244,6 +269,15 @@ func TestParseSection(t *testing.T) {
 		{"[section] # comment", Config{Global: {}, "section": {}}},
 		{"[sec;tion]", Config{Global: {}, "sec;tion": {}}},
 		{"[ s e c t i o n ]", Config{Global: {}, "s e c t i o n": {}}},
+		{`["weird ] name"]`, Config{Global: {}, "weird ] name": {}}},
+		{`[ " spaces ; and \"quotes\" " ] ; comment`, Config{Global: {}, ` spaces ; and "quotes" `: {}}},
+		{`['single \n quotes']`, Config{Global: {}, "single \n quotes": {}}},
+		{`["new\nline\x00"]`, Config{Global: {}, "new\nline\x00": {}}},
+		{`[remote "origin"]`, Config{Global: {}, `remote "origin"`: {}}},
+		{`["my.app".sub]`, Config{Global: {}, `"my.app".sub`: {}}},
+		{`["a" "b"]`, Config{Global: {}, `"a" "b"`: {}}},
+		{`["a"]]`, Config{Global: {}, `"a"]`: {}}},
+		{`["unclosed]`, Config{Global: {}, `"unclosed`: {}}},
 	}
 
 	if err := testParser(tests); err != nil {
@@ -251,9 +285,45 @@ func TestParseSection(t *testing.T) {
 	}
 }
 
+func TestParseContinuation(t *testing.T) {
+	t.Parallel()
+	opts := Options{BackslashContinuation: true, IndentedContinuation: true}
+	tests := []ParseTest{
+		{"key=first \\\nsecond", Config{Global: {"key": "first second"}}}, // Backslash.
+		{"key=first \\\n\tsecond", Config{Global: {"key": "first second"}}},
+		{"key=first \\\r\nsecond\r\n", Config{Global: {"key": "first second"}}},
+		{"key=1\\\n2\\\n3\nkey2=value", Config{Global: {"key": "123", "key2": "value"}}},
+		{"key=\"first \\\nsecond\" ; comment", Config{Global: {"key": "first second"}}},
+		{"key=\\\nvalue", Config{Global: {"key": "value"}}},
+		{"ke\\\ny=value", Config{Global: {"key": "value"}}},
+		{"key=value\\\\\nkey2=value2", Config{Global: {"key": `value\`, "key2": "value2"}}},
+		{"key=value\\", Config{Global: {"key": "value"}}},
+		{"; comment \\\nkey=value", Config{Global: {"key": "value"}}},
+		{"key=first\n\tsecond\n\tthird", Config{Global: {"key": "first\nsecond\nthird"}}}, // Indented.
+		{"key=first\n  second ; comment\nkey2=value",
+			Config{Global: {"key": "first\nsecond", "key2": "value"}}},
+		{"key=first\n  \" second \"", Config{Global: {"key": "first\n second "}}},
+		{"\tkey=first\n\t\tsecond\n\tkey2=value",
+			Config{Global: {"key": "first\nsecond", "key2": "value"}}},
+		{"key=first\n\t; comment\n\tsecond", Config{Global: {"key": "first\nsecond"}}},
+		{"key=first\n\n\tkey2=value", Config{Global: {"key": "first", "key2": "value"}}},
+		{"[section]\n\tkey=value", Config{Global: {}, "section": {"key": "value"}}},
+		{"key=first\n\tsecond \\\n\tthird", Config{Global: {"key": "first\nsecond third"}}},
+		{"key=first\n  [section]\n  key=value", Config{Global: {"key": "first"}, "section": {"key": "value"}}},
+	}
+
+	if err := testParserWithOptions(tests, opts); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
 func testParser(tests []ParseTest) error {
+	return testParserWithOptions(tests, Options{})
+}
+
+func testParserWithOptions(tests []ParseTest, opts Options) error {
 	for _, test := range tests {
-		config, err := Parse(strings.NewReader(test.content))
+		config, err := ParseWithOptions(strings.NewReader(test.content), opts)
 		if err != nil {
 			return fmt.Errorf("Unexpected error from Parse(%s): %s", test.content, err.Error())
 		}
@@ -301,7 +371,18 @@ func TestParseError(t *testing.T) {
 		{"[section] информации", "ini: syntax error on line 1: unexpected \"и\" after section closed"},
 		{"[]", "ini: syntax error on line 1: section can't be empty"},
 		{"[ ]", "ini: syntax error on line 1: section can't be empty"},
+		{`[""]`, "ini: syntax error on line 1: section can't be empty"},
+		{`["a ] b"] c`, "ini: syntax error on line 1: unexpected \"c\" after section closed"},
 		{"[section]\n[section]", "ini: syntax error on line 2: section \"section\" already exists"},
+		{"key=value\\\n\"key2=value", "ini: syntax error on line 2: quote not closed"}, // Continuation lines.
+		{"key=\"value\\\n\\\nvalue2", "ini: syntax error on line 1: quote not closed"},
+		{"[section\\\n] a", "ini: syntax error on line 1: unclosed section"},
+		{"key=value\n\t\"value2", "ini: syntax error on line 2: quote not closed"},
+		{`key="\x4"`, `ini: syntax error on line 1: invalid escape sequence "\\x4"`}, // Escapes.
+		{`key="\xZZ"`, `ini: syntax error on line 1: invalid escape sequence "\\x"`},
+		{`key="\u12"`, `ini: syntax error on line 1: invalid escape sequence "\\u12"`},
+		{`key="\uD800"`, `ini: syntax error on line 1: invalid escape sequence "\\uD800"`},
+		{`key="\19"`, `ini: syntax error on line 1: invalid escape sequence "\\19"`},
 	}
 
 	for _, test := range tests {
@@ -336,3 +417,41 @@ func TestParseIOError(t *testing.T) {
 			errMsg, err.Error())
 	}
 }
+
+func TestParseErrorColumn(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		content string
+		line    int
+		column  int
+		source  string
+	}{
+		{"key=value\nkey=value2", 2, 0, ""},
+		{"=value", 1, 1, "=value"},
+		{`key="value`, 1, 5, `key="value`},
+		{"  \"key\" x= value", 1, 9, "  \"key\" x= value"},
+		{`"продавливания" информации`, 1, 17, `"продавливания" информации`},
+		{"[section] информации", 1, 11, "[section] информации"},
+		{"  [section", 1, 11, "  [section"},
+		{"key=value\\\n\t\"key2=value\r\n", 2, 2, "\t\"key2=value"},
+		{"[section\\\n  ] a", 2, 5, "  ] a"},
+		{`key="\x4"`, 1, 6, `key="\x4"`},
+	}
+
+	opts := Options{BackslashContinuation: true}
+	for _, test := range tests {
+		_, err := ParseWithOptions(strings.NewReader(test.content), opts)
+		synErr, ok := err.(SyntaxError)
+		if !ok {
+			t.Fatalf("Expected Parse(%q) to return a syntax error, but got %v",
+				test.content, err)
+		}
+
+		if synErr.LineNumber != test.line || synErr.Column != test.column ||
+			synErr.Source != test.source {
+			t.Fatalf("Expected Parse(%q) to return an error at %d:%d in %q, but got %d:%d in %q",
+				test.content, test.line, test.column, test.source,
+				synErr.LineNumber, synErr.Column, synErr.Source)
+		}
+	}
+}
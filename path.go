@@ -0,0 +1,126 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"sort"
+	"strings"
+)
+
+// SplitSection splits a section name into the path of the section in the
+// section hierarchy. Both dotted names and git-config style quoted
+// subsections are supported, like so:
+//
+//	"database"           -> []string{"database"}
+//	"database.replica"   -> []string{"database", "replica"}
+//	`remote "origin"`    -> []string{"remote", "origin"}
+//	`remote "my.origin"` -> []string{"remote", "my.origin"}
+//
+// Inside quotes a backslash escapes the next character. The global section
+// has an empty path.
+func SplitSection(name string) []string {
+	var path []string
+	var element []byte
+	var afterQuote bool
+
+	for i := 0; i < len(name); i++ {
+		switch b := name[i]; b {
+		case '.':
+			if trimmed := strings.TrimSpace(string(element)); trimmed != "" || !afterQuote {
+				path = append(path, trimmed)
+			}
+			element = element[:0]
+			afterQuote = false
+		case doubleQuote:
+			if trimmed := strings.TrimSpace(string(element)); trimmed != "" {
+				path = append(path, trimmed)
+			}
+			element = element[:0]
+
+			var quoted []byte
+			for i++; i < len(name) && name[i] != doubleQuote; i++ {
+				if name[i] == escape && i+1 < len(name) {
+					i++
+				}
+				quoted = append(quoted, name[i])
+			}
+			path = append(path, string(quoted))
+			afterQuote = true
+		default:
+			element = append(element, b)
+		}
+	}
+
+	if trimmed := strings.TrimSpace(string(element)); trimmed != "" ||
+		(!afterQuote && len(path) != 0) {
+		path = append(path, trimmed)
+	}
+	return path
+}
+
+// PathKey returns a single string for the path, to be used as map key.
+func pathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// Children returns the names of the sections directly below the section in
+// the section hierarchy, sorted alphabetically. See SplitSection for how the
+// hierarchy is determined.
+//
+//	[database]
+//	[database.replica]
+//	[database "backup"]
+//
+// With the configuration above the children of "database" are
+// "database.replica" and `database "backup"`. The children of the Global
+// section are all top level sections.
+func (c Config) Children(section string) []string {
+	parent := SplitSection(section)
+	var children []string
+	for name := range c {
+		path := SplitSection(name)
+		if len(path) == len(parent)+1 && isPrefix(parent, path) {
+			children = append(children, name)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// Lookup looks up the value of a key by its path, which is the path of the
+// section followed by the key, see SplitSection. For example
+// "database.replica.host" looks up the key "host" in the section
+// "database.replica", `database "replica"` or `database."replica"`. Keys in
+// the global section can be looked up by their key alone.
+func (c Config) Lookup(path string) (string, bool) {
+	parts := SplitSection(path)
+	if len(parts) == 0 {
+		return "", false
+	}
+	sectionPath, key := pathKey(parts[:len(parts)-1]), parts[len(parts)-1]
+
+	for _, name := range getConfigSectionsAlpha(c) {
+		if pathKey(SplitSection(name)) != sectionPath {
+			continue
+		}
+		if value, ok := c[name][key]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// IsPrefix checks if prefix is a prefix of path.
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, element := range prefix {
+		if path[i] != element {
+			return false
+		}
+	}
+	return true
+}
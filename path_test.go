@@ -0,0 +1,117 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSection(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{Global, nil},
+		{"database", []string{"database"}},
+		{"database.replica", []string{"database", "replica"}},
+		{"a.b.c", []string{"a", "b", "c"}},
+		{"a . b", []string{"a", "b"}},
+		{"s e c t i o n", []string{"s e c t i o n"}},
+		{`remote "origin"`, []string{"remote", "origin"}},
+		{`remote "my.origin"`, []string{"remote", "my.origin"}},
+		{`remote "with \"quote\""`, []string{"remote", `with "quote"`}},
+		{`a.b "c.d"`, []string{"a", "b", "c.d"}},
+		{`a."b".c`, []string{"a", "b", "c"}},
+		{`"a"`, []string{"a"}},
+		{"a..b", []string{"a", "", "b"}},
+		{"a.", []string{"a", ""}},
+	}
+
+	for _, test := range tests {
+		got := SplitSection(test.name)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Fatalf("Expected SplitSection(%q) to return %q, but got %q",
+				test.name, test.expected, got)
+		}
+	}
+}
+
+const hierarchyContent = `key = global
+[database]
+host = db
+[database.replica]
+host = replica
+[database.replica.eu]
+host = eu
+[database "backup"]
+host = backup
+[remote "my.origin"]
+url = example.com
+`
+
+func TestConfigChildren(t *testing.T) {
+	t.Parallel()
+	c, err := Parse(strings.NewReader(hierarchyContent))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	tests := []struct {
+		section  string
+		expected []string
+	}{
+		{Global, []string{"database"}},
+		{"database", []string{`database "backup"`, "database.replica"}},
+		{`database "replica"`, []string{"database.replica.eu"}},
+		{"database.replica.eu", nil},
+		{"remote", []string{`remote "my.origin"`}},
+		{"unknown", nil},
+	}
+
+	for _, test := range tests {
+		got := c.Children(test.section)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Fatalf("Expected Config.Children(%q) to return %q, but got %q",
+				test.section, test.expected, got)
+		}
+	}
+}
+
+func TestConfigLookup(t *testing.T) {
+	t.Parallel()
+	c, err := Parse(strings.NewReader(hierarchyContent))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %s", err.Error())
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+		found    bool
+	}{
+		{"key", "global", true},
+		{"database.host", "db", true},
+		{"database.replica.host", "replica", true},
+		{`database."replica".host`, "replica", true},
+		{"database.replica.eu.host", "eu", true},
+		{"database.backup.host", "backup", true},
+		{`remote."my.origin".url`, "example.com", true},
+		{"remote.my.origin.url", "", false},
+		{"database.port", "", false},
+		{"unknown.key", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		got, found := c.Lookup(test.path)
+		if got != test.expected || found != test.found {
+			t.Fatalf("Expected Config.Lookup(%q) to return %q, %t, but got %q, %t",
+				test.path, test.expected, test.found, got, found)
+		}
+	}
+}
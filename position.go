@@ -0,0 +1,39 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import "strconv"
+
+// Position is a position in the source of a configuration.
+type Position struct {
+	// File is the name of the file, only set when parsing with ParseFS or
+	// ParseDocumentFS.
+	File string
+	// Line and Column both start at one, the column is in runes.
+	Line   int
+	Column int
+}
+
+// IsValid checks if the position is known.
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+// String returns the position as "file:line:column", leaving out the unknown
+// parts, or "-" if the position isn't known.
+func (pos Position) String() string {
+	if !pos.IsValid() {
+		return "-"
+	}
+
+	s := strconv.Itoa(pos.Line)
+	if pos.File != "" {
+		s = pos.File + ":" + s
+	}
+	if pos.Column != 0 {
+		s += ":" + strconv.Itoa(pos.Column)
+	}
+	return s
+}
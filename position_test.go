@@ -0,0 +1,29 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import "testing"
+
+func TestPositionString(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{}, "-"},
+		{Position{File: "app.ini"}, "-"},
+		{Position{Line: 1}, "1"},
+		{Position{Line: 1, Column: 2}, "1:2"},
+		{Position{File: "app.ini", Line: 1}, "app.ini:1"},
+		{Position{File: "app.ini", Line: 1, Column: 2}, "app.ini:1:2"},
+	}
+
+	for _, test := range tests {
+		if got := test.pos.String(); got != test.expected {
+			t.Fatalf("Expected %#v.String() to return %q, but got %q",
+				test.pos, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import "io"
+
+// Scanner reads ini formatted input one node at a time, without keeping the
+// sections and keys in memory. This allows large files to be filtered or
+// transformed in constant memory.
+//
+//	s := ini.NewScanner(r)
+//	for {
+//		n, err := s.Next()
+//		if err == io.EOF {
+//			break
+//		} else if err != nil {
+//			// Handle error.
+//		}
+//		if n.Kind == ini.KeyValueNode {
+//			fmt.Printf("%s: %s.%s = %s\n", n.Position(), n.Section, n.Key, n.Value)
+//		}
+//	}
+//
+// The nodes are the same as the nodes of a Document, see Document.Nodes. As
+// the scanner doesn't remember the sections and keys it has seen, duplicate
+// sections and keys aren't detected and Limits.MaxSections and
+// Limits.MaxKeysPerSection aren't enforced. Includes and inheritance of
+// sections aren't supported either.
+type Scanner struct {
+	p *parser
+	// Syntax errors of the error nodes that aren't returned yet.
+	errs map[*Node]error
+	// Error reading the input, or io.EOF.
+	err error
+}
+
+// NewScanner returns a new scanner reading from the reader. The same rules as
+// Parse apply.
+func NewScanner(r io.Reader) *Scanner {
+	// The default options are always valid.
+	s, _ := NewScannerWithOptions(r, Options{})
+	return s
+}
+
+// NewScannerWithOptions returns a new scanner reading from the reader, using
+// the options to determine the syntax. It returns an error if the options are
+// invalid.
+func NewScannerWithOptions(r io.Reader, opts Options) (*Scanner, error) {
+	p, err := newParser(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.stream = true
+	p.doc = &Document{opts: p.opts}
+	return &Scanner{p: p, errs: map[*Node]error{}}, nil
+}
+
+// Next returns the next node. At the end of the input it returns io.EOF.
+//
+// A line with a syntax error is returned as an error node, along with the
+// SyntaxError. The line is skipped and scanning can continue with the next
+// line. Any other error, such as an error reading the input or a LimitError,
+// is returned by all following calls.
+//
+// A key-value node is only returned once the next line is read, as the value
+// may continue on that line.
+func (s *Scanner) Next() (Node, error) {
+	for {
+		if n := s.complete(); n != nil {
+			err := s.errs[n]
+			delete(s.errs, n)
+			return *n, err
+		}
+
+		if s.err != nil {
+			return Node{}, s.err
+		}
+		s.readLine()
+	}
+}
+
+// Complete removes and returns the first node if it's complete, i.e. it can't
+// be continued on a following line.
+func (s *Scanner) complete() *Node {
+	nodes := s.p.doc.nodes
+	if len(nodes) == 0 || nodes[0] == s.p.lastKeyValue {
+		return nil
+	}
+
+	n := nodes[0]
+	nodes[0] = nil
+	s.p.doc.nodes = nodes[1:]
+	return n
+}
+
+// ReadLine reads and parses the next line, adding the nodes to the document
+// of the parser.
+func (s *Scanner) readLine() {
+	p := s.p
+	line, ok := p.lines.next()
+	if !ok {
+		// Nothing can be continued anymore.
+		p.lastKeyValue = nil
+		if s.err = p.readError(); s.err == nil {
+			s.err = io.EOF
+		}
+		return
+	}
+
+	p.added = false
+	if err := p.handleLine(line); err != nil {
+		if limitErr, ok := err.(LimitError); ok {
+			p.lastKeyValue = nil
+			s.err = p.limitError(limitErr, line.lineNumber)
+			return
+		}
+
+		synErr := p.syntaxError(line, err)
+		p.skipLine(line)
+		s.errs[p.doc.nodes[len(p.doc.nodes)-1]] = synErr
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2015-2016 Thomas de Zeeuw.
+//
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+	content := "; comment\nkey = value ; inline\n\n[section]\n  multi = first\n" +
+		"  ; comment\n    second\n  broken\n  key = value\n; last"
+	s, err := NewScannerWithOptions(strings.NewReader(content), Options{IndentedContinuation: true})
+	if err != nil {
+		t.Fatalf("Unexpected error creating scanner: %s", err.Error())
+	}
+
+	expected := []struct {
+		node Node
+		err  string
+	}{
+		{Node{Kind: CommentNode, Comment: "; comment", Line: 1, Column: 1}, ""},
+		{Node{Kind: KeyValueNode, Key: "key", Value: "value", Comment: "; inline",
+			Line: 2, Column: 1}, ""},
+		{Node{Kind: BlankNode, Line: 3}, ""},
+		{Node{Kind: SectionNode, Section: "section", Line: 4, Column: 1}, ""},
+		{Node{Kind: KeyValueNode, Section: "section", Key: "multi",
+			Value: "first\nsecond", Line: 5, Column: 3}, ""},
+		{Node{Kind: ErrorNode, Line: 8, Column: 3},
+			"ini: syntax error on line 8: no separator found"},
+		{Node{Kind: KeyValueNode, Section: "section", Key: "key", Value: "value",
+			Line: 9, Column: 3}, ""},
+		{Node{Kind: CommentNode, Comment: "; last", Line: 10, Column: 1}, ""},
+	}
+
+	for i, e := range expected {
+		n, err := s.Next()
+		if e.err == "" && err != nil {
+			t.Fatalf("Unexpected error scanning node %d: %s", i, err.Error())
+		} else if e.err != "" && (err == nil || err.Error() != e.err) {
+			t.Fatalf("Expected error %q scanning node %d, but got %v", e.err, i, err)
+		}
+
+		if n.Kind != e.node.Kind || n.Section != e.node.Section || n.Key != e.node.Key ||
+			n.Value != e.node.Value || n.Comment != e.node.Comment ||
+			n.Position() != e.node.Position() {
+			t.Fatalf("Expected node %d to be %+v, but got %+v", i, e.node, n)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Next(); err != io.EOF {
+			t.Fatalf("Expected io.EOF at the end of the input, but got %v", err)
+		}
+	}
+}
+
+func TestScannerMemory(t *testing.T) {
+	t.Parallel()
+	r, w := io.Pipe()
+	go func() {
+		for i := 0; i < 1000; i++ {
+			io.WriteString(w, "[section]\nkey = value\nkey = value\n")
+		}
+		w.Close()
+	}()
+
+	s := NewScanner(r)
+	var n int
+	for {
+		_, err := s.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Unexpected error scanning: %s", err.Error())
+		}
+		n++
+
+		if len(s.p.doc.nodes) > 1 || len(s.p.Config) > 1 {
+			t.Fatalf("Expected the scanner to not keep any nodes, but got %d nodes "+
+				"and %d sections", len(s.p.doc.nodes), len(s.p.Config))
+		}
+	}
+
+	if n != 3000 {
+		t.Fatalf("Expected 3000 nodes, but got %d", n)
+	}
+}
+
+func TestScannerWithOptions(t *testing.T) {
+	t.Parallel()
+	if _, err := NewScannerWithOptions(strings.NewReader(""), Options{Separators: " "}); err == nil {
+		t.Fatal("Expected an error for invalid options")
+	}
+
+	opts := Options{Separators: ":", Limits: Limits{MaxLineLength: 10}}
+	s, err := NewScannerWithOptions(strings.NewReader("key: value\nkey: too long"), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error creating scanner: %s", err.Error())
+	}
+
+	if n, err := s.Next(); err != nil || n.Value != "value" {
+		t.Fatalf("Expected the first node, but got %+v, %v", n, err)
+	}
+	for i := 0; i < 2; i++ {
+		var limitErr LimitError
+		if _, err := s.Next(); !errors.As(err, &limitErr) {
+			t.Fatalf("Expected a LimitError, but got %v", err)
+		}
+	}
+}